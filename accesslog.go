@@ -0,0 +1,151 @@
+package httprouter
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type LogFormat int
+
+const (
+	LogFormatStructured LogFormat = iota
+	LogFormatCommon
+	LogFormatCombined
+)
+
+var defaultRedactedHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Api-Key",
+	"X-Csrf-Token",
+}
+
+type AccessLogOptions struct {
+	// Logger receives the structured logs, defaults to slog.Default()
+	Logger *slog.Logger
+
+	Format LogFormat
+
+	// Output receives the Common and Combined Log Format lines, defaults to os.Stdout
+	Output io.Writer
+
+	// SampleRate is the fraction of requests below 400 that are logged, all requests are logged if it is 0
+	SampleRate float64
+
+	// Level chooses the level of a structured log from the response status
+	Level func(status int) slog.Level
+
+	// LogHeaders adds the request headers to structured logs
+	LogHeaders bool
+
+	// RedactHeaders are redacted in addition to the default sensitive headers such as Authorization and Cookie
+	RedactHeaders []string
+}
+
+func statusLevel(status int) slog.Level {
+	switch {
+	case status >= 500:
+		return slog.LevelError
+	case status >= 400:
+		return slog.LevelWarn
+	default:
+		return slog.LevelInfo
+	}
+}
+
+func redactHeaders(header http.Header, redacted []string) http.Header {
+	header = header.Clone()
+	for _, name := range redacted {
+		if _, ok := header[http.CanonicalHeaderKey(name)]; ok {
+			header.Set(name, "[REDACTED]")
+		}
+	}
+	return header
+}
+
+func AccessLogMiddleware(opts AccessLogOptions) Middleware {
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	if opts.Output == nil {
+		opts.Output = os.Stdout
+	}
+	if opts.Level == nil {
+		opts.Level = statusLevel
+	}
+	redacted := append(append([]string{}, defaultRedactedHeaders...), opts.RedactHeaders...)
+	var mu sync.Mutex
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rw := WrapResponseWriter(w)
+			next.ServeHTTP(rw, r)
+			duration := time.Since(start)
+
+			status := rw.Status()
+			if opts.SampleRate > 0 && status < 400 && rand.Float64() >= opts.SampleRate {
+				return
+			}
+
+			switch opts.Format {
+			case LogFormatCommon, LogFormatCombined:
+				line := commonLogLine(r, status, rw.BytesWritten(), start)
+				if opts.Format == LogFormatCombined {
+					line += fmt.Sprintf(" %q %q", r.Referer(), r.UserAgent())
+				}
+				mu.Lock()
+				io.WriteString(opts.Output, line+"\n")
+				mu.Unlock()
+			default:
+				attrs := []slog.Attr{
+					slog.String("method", r.Method),
+					slog.String("route", routePattern(r)),
+					slog.String("path", r.URL.EscapedPath()),
+					slog.Int("status", status),
+					slog.Int64("bytes", rw.BytesWritten()),
+					slog.Duration("duration", duration),
+					slog.String("remote_ip", ClientIP(r)),
+				}
+				if id := RequestID(r); id != "" {
+					attrs = append(attrs, slog.String("request_id", id))
+				}
+				if opts.LogHeaders {
+					attrs = append(attrs, slog.Any("headers", redactHeaders(r.Header, redacted)))
+				}
+				opts.Logger.LogAttrs(r.Context(), opts.Level(status), "request", attrs...)
+			}
+		})
+	}
+}
+
+func commonLogLine(r *http.Request, status int, bytes int64, start time.Time) string {
+	user := "-"
+	if username, _, ok := r.BasicAuth(); ok && username != "" {
+		user = strings.ReplaceAll(username, " ", "_")
+	}
+	size := "-"
+	if bytes > 0 {
+		size = fmt.Sprintf("%d", bytes)
+	}
+	return fmt.Sprintf(
+		"%s - %s [%s] \"%s %s %s\" %d %s",
+		ClientIP(r),
+		user,
+		start.Format("02/Jan/2006:15:04:05 -0700"),
+		r.Method,
+		r.URL.RequestURI(),
+		r.Proto,
+		status,
+		size,
+	)
+}
@@ -0,0 +1,122 @@
+package httprouter
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestAccessLogStructured(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+
+	r := NewRouter()
+	r.Use(AccessLogMiddleware(AccessLogOptions{Logger: logger, LogHeaders: true}))
+	r.Post("/products", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("Created"))
+	})
+
+	req := httptest.NewRequest("POST", "/products", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("Accept", "text/plain")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	var entry map[string]any
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatalf("Failed to parse log entry %s: %v", logs.String(), err)
+	}
+
+	expected := map[string]any{
+		"level":     "INFO",
+		"msg":       "request",
+		"method":    "POST",
+		"route":     "/products",
+		"path":      "/products",
+		"status":    float64(201),
+		"bytes":     float64(7),
+		"remote_ip": "192.0.2.1",
+	}
+	for key, value := range expected {
+		if entry[key] != value {
+			t.Errorf("Expected %s to be %v but got %v", key, value, entry[key])
+		}
+	}
+
+	headers, _ := entry["headers"].(map[string]any)
+	if auth, _ := headers["Authorization"].([]any); len(auth) != 1 || auth[0] != "[REDACTED]" {
+		t.Errorf("Expected the Authorization header to be redacted but got %v", headers["Authorization"])
+	}
+	if accept, _ := headers["Accept"].([]any); len(accept) != 1 || accept[0] != "text/plain" {
+		t.Errorf("Expected the Accept header to be logged but got %v", headers["Accept"])
+	}
+}
+
+func TestAccessLogLevels(t *testing.T) {
+	type Test struct {
+		status int
+		level  string
+	}
+
+	testTable := []Test{
+		{status: http.StatusOK, level: "INFO"},
+		{status: http.StatusNotFound, level: "WARN"},
+		{status: http.StatusBadGateway, level: "ERROR"},
+	}
+
+	for i, test := range testTable {
+		var logs bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&logs, nil))
+		handler := AccessLogMiddleware(AccessLogOptions{Logger: logger, SampleRate: 0.000001})(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(test.status)
+			}))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+		var entry map[string]any
+		json.Unmarshal(logs.Bytes(), &entry)
+
+		// successful requests are almost certainly sampled out while errors are always logged
+		if test.status < 400 && logs.Len() != 0 {
+			t.Errorf("Failed test %d, expected the request to be sampled out", i)
+		} else if test.status >= 400 && entry["level"] != test.level {
+			t.Errorf("Failed test %d, expected level %s but got %v", i, test.level, entry["level"])
+		}
+	}
+}
+
+func TestAccessLogCommonFormats(t *testing.T) {
+	type Test struct {
+		format  LogFormat
+		pattern string
+	}
+
+	testTable := []Test{
+		{format: LogFormatCommon,
+			pattern: `^192\.0\.2\.1 - alice \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "GET /ping\?x=1 HTTP/1\.1" 200 5\n$`},
+		{format: LogFormatCombined,
+			pattern: `^192\.0\.2\.1 - alice \[.+\] "GET /ping\?x=1 HTTP/1\.1" 200 5 "http://example\.com/" "test-agent"\n$`},
+	}
+
+	for i, test := range testTable {
+		var out bytes.Buffer
+		handler := AccessLogMiddleware(AccessLogOptions{Format: test.format, Output: &out})(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("Pong!"))
+			}))
+
+		req := httptest.NewRequest("GET", "/ping?x=1", nil)
+		req.SetBasicAuth("alice", "password")
+		req.Header.Set("Referer", "http://example.com/")
+		req.Header.Set("User-Agent", "test-agent")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		if !regexp.MustCompile(test.pattern).MatchString(out.String()) {
+			t.Errorf("Failed test %d, expected log line matching %s but got %q", i, test.pattern, out.String())
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package httprouter
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// Principal is the authenticated client of a request
+type Principal struct {
+	Subject string
+	Scheme  string
+
+	// Roles and Permissions are used by AuthorizeMiddleware to enforce the permissions routes require
+	Roles       []string
+	Permissions []string
+}
+
+func withPrincipal(r *http.Request, principal *Principal) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), principalKey, principal))
+}
+
+// CurrentPrincipal is the client authenticated by an authentication middleware, or nil if there is none
+func CurrentPrincipal(r *http.Request) *Principal {
+	principal, _ := r.Context().Value(principalKey).(*Principal)
+	return principal
+}
+
+// compares the hashes of the secrets so the time taken doesn't leak their contents or lengths
+func secureCompare(given string, expected string) bool {
+	a := sha256.Sum256([]byte(given))
+	b := sha256.Sum256([]byte(expected))
+	return subtle.ConstantTimeCompare(a[:], b[:]) == 1
+}
+
+// BasicAuthUsers validates credentials against a map of usernames to passwords in constant time
+func BasicAuthUsers(users map[string]string) func(username string, password string) bool {
+	return func(username string, password string) bool {
+		expected, ok := users[username]
+		if !ok {
+			// compare anyway so unknown users take as long as known users
+			secureCompare(password, "")
+			return false
+		}
+		return secureCompare(password, expected)
+	}
+}
+
+func BasicAuthMiddleware(realm string, validate func(username string, password string) bool) Middleware {
+	if validate == nil {
+		panic("httprouter: basic auth middleware requires a validate function")
+	}
+	challenge := `Basic realm="` + strings.ReplaceAll(realm, `"`, `\"`) + `", charset="UTF-8"`
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			username, password, ok := r.BasicAuth()
+			if !ok || !validate(username, password) {
+				w.Header().Set("WWW-Authenticate", challenge)
+				HandleError(w, r, ErrUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, withPrincipal(r, &Principal{Subject: username, Scheme: "basic"}))
+		})
+	}
+}
+
+type APIKeyOptions struct {
+	// Header, Query and Cookie are checked in that order for the key, Header defaults to X-API-Key if none are set
+	Header string
+	Query  string
+	Cookie string
+
+	// Validate returns the subject the key belongs to
+	Validate func(key string) (subject string, ok bool)
+}
+
+// APIKeys validates keys against a map of keys to subjects in constant time
+func APIKeys(keys map[string]string) func(key string) (string, bool) {
+	return func(key string) (string, bool) {
+		subject := ""
+		found := false
+		// every key is compared so the time taken doesn't depend on which key matched
+		for expected, s := range keys {
+			if secureCompare(key, expected) {
+				subject = s
+				found = true
+			}
+		}
+		return subject, found
+	}
+}
+
+func (opts *APIKeyOptions) key(r *http.Request) string {
+	if opts.Header != "" {
+		if key := r.Header.Get(opts.Header); key != "" {
+			return key
+		}
+	}
+	if opts.Query != "" {
+		if key := r.URL.Query().Get(opts.Query); key != "" {
+			return key
+		}
+	}
+	if opts.Cookie != "" {
+		if cookie, err := r.Cookie(opts.Cookie); err == nil {
+			return cookie.Value
+		}
+	}
+	return ""
+}
+
+func APIKeyMiddleware(opts APIKeyOptions) Middleware {
+	if opts.Validate == nil {
+		panic("httprouter: api key middleware requires a validate function")
+	}
+	if opts.Header == "" && opts.Query == "" && opts.Cookie == "" {
+		opts.Header = "X-API-Key"
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := opts.key(r)
+			if key == "" {
+				HandleError(w, r, ErrUnauthorized)
+				return
+			}
+			subject, ok := opts.Validate(key)
+			if !ok {
+				HandleError(w, r, ErrUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, withPrincipal(r, &Principal{Subject: subject, Scheme: "apikey"}))
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package httprouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBasicAuthMiddleware(t *testing.T) {
+	r := NewRouter()
+	r.Use(BasicAuthMiddleware(`Internal "Tools"`, BasicAuthUsers(map[string]string{"alice": "wonderland"})))
+	r.Get("/tools", func(w http.ResponseWriter, r *http.Request) {
+		principal := CurrentPrincipal(r)
+		w.Write([]byte(principal.Scheme + " " + principal.Subject))
+	})
+
+	type Test struct {
+		username string
+		password string
+		status   int
+		bodyOut  string
+	}
+
+	testTable := []Test{
+		{username: "alice", password: "wonderland", status: http.StatusOK, bodyOut: "basic alice"},
+		{username: "alice", password: "wonder", status: http.StatusUnauthorized, bodyOut: "401 unauthorized"},
+		{username: "bob", password: "wonderland", status: http.StatusUnauthorized, bodyOut: "401 unauthorized"},
+		{status: http.StatusUnauthorized, bodyOut: "401 unauthorized"},
+	}
+
+	for i, test := range testTable {
+		req := httptest.NewRequest("GET", "/tools", nil)
+		if test.username != "" {
+			req.SetBasicAuth(test.username, test.password)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != test.status || w.Body.String() != test.bodyOut {
+			t.Errorf("Failed test %d, expected %d %q, got %d %q", i, test.status, test.bodyOut, w.Code, w.Body.String())
+		}
+		challenge := w.Header().Get("WWW-Authenticate")
+		if test.status == http.StatusUnauthorized && challenge != `Basic realm="Internal \"Tools\"", charset="UTF-8"` {
+			t.Errorf("Failed test %d, expected a Basic challenge but got %q", i, challenge)
+		}
+	}
+}
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	keys := APIKeys(map[string]string{"key-1": "service-a", "key-2": "service-b"})
+
+	r := NewRouter()
+	r.Use(APIKeyMiddleware(APIKeyOptions{Header: "X-API-Key", Query: "api_key", Cookie: "api_key", Validate: keys}))
+	r.Get("/data", func(w http.ResponseWriter, r *http.Request) {
+		principal := CurrentPrincipal(r)
+		w.Write([]byte(principal.Scheme + " " + principal.Subject))
+	})
+
+	type Test struct {
+		url     string
+		header  string
+		cookie  string
+		status  int
+		bodyOut string
+	}
+
+	testTable := []Test{
+		{url: "/data", header: "key-1", status: http.StatusOK, bodyOut: "apikey service-a"},
+		{url: "/data?api_key=key-2", status: http.StatusOK, bodyOut: "apikey service-b"},
+		{url: "/data", cookie: "key-1", status: http.StatusOK, bodyOut: "apikey service-a"},
+		{url: "/data?api_key=key-1", header: "key-2", status: http.StatusOK, bodyOut: "apikey service-b"},
+		{url: "/data", header: "key-3", status: http.StatusUnauthorized, bodyOut: "401 unauthorized"},
+		{url: "/data", status: http.StatusUnauthorized, bodyOut: "401 unauthorized"},
+	}
+
+	for i, test := range testTable {
+		req := httptest.NewRequest("GET", test.url, nil)
+		if test.header != "" {
+			req.Header.Set("X-API-Key", test.header)
+		}
+		if test.cookie != "" {
+			req.AddCookie(&http.Cookie{Name: "api_key", Value: test.cookie})
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != test.status || w.Body.String() != test.bodyOut {
+			t.Errorf("Failed test %d, expected %d %q, got %d %q", i, test.status, test.bodyOut, w.Code, w.Body.String())
+		}
+	}
+
+	if CurrentPrincipal(httptest.NewRequest("GET", "/", nil)) != nil {
+		t.Errorf("Expected no principal for an unauthenticated request")
+	}
+}
+
+func TestAuthMiddlewareWithoutValidate(t *testing.T) {
+	testTable := []func(){
+		func() { BasicAuthMiddleware("tools", nil) },
+		func() { APIKeyMiddleware(APIKeyOptions{Header: "X-API-Key"}) },
+	}
+
+	for i, build := range testTable {
+		func() {
+			defer func() {
+				if rec := recover(); rec == nil {
+					t.Errorf("Failed test %d, expected a middleware without a validate function to panic", i)
+				}
+			}()
+			build()
+		}()
+	}
+}
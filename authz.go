@@ -0,0 +1,77 @@
+package httprouter
+
+import "net/http"
+
+// Authorizer reports whether the principal holds the permission
+type Authorizer = func(r *http.Request, principal *Principal, permission string) bool
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPermission authorizes principals whose Permissions include the permission
+func HasPermission(r *http.Request, principal *Principal, permission string) bool {
+	return contains(principal.Permissions, permission)
+}
+
+// RolePermissions authorizes principals that hold the permission directly or through one of their Roles
+func RolePermissions(grants map[string][]string) Authorizer {
+	return func(r *http.Request, principal *Principal, permission string) bool {
+		if contains(principal.Permissions, permission) {
+			return true
+		}
+		for _, role := range principal.Roles {
+			if contains(grants[role], permission) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// AuthorizeMiddleware enforces the permissions the matched route requires, responding with a 401 when there is no principal
+// and a 403 when the principal lacks a permission. It must run after the authentication middleware, authorize defaults to HasPermission
+func AuthorizeMiddleware(authorize Authorizer) Middleware {
+	if authorize == nil {
+		authorize = HasPermission
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rt := matchedRoute(r)
+			if rt == nil || len(rt.meta.permissions) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			principal := CurrentPrincipal(r)
+			if principal == nil {
+				HandleError(w, r, ErrUnauthorized)
+				return
+			}
+			for _, permission := range rt.meta.permissions {
+				if !authorize(r, principal, permission) {
+					HandleError(w, r, ErrForbidden)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// UnprotectedRoutes lists the routes that don't require any permissions, as "METHOD pattern"
+func (router *ServerRouter) UnprotectedRoutes() []string {
+	routes := make([]string, 0)
+	router.Walk(func(route RouteInfo) error {
+		if len(route.Permissions) == 0 {
+			routes = append(routes, route.Method+" "+route.Pattern)
+		}
+		return nil
+	})
+	return routes
+}
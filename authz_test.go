@@ -0,0 +1,134 @@
+package httprouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func createAuthzTestRouter() *ServerRouter {
+	r := NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			subject := r.Header.Get("X-Subject")
+			if subject == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			principal := &Principal{Subject: subject, Scheme: "test"}
+			if roles := r.Header.Get("X-Roles"); roles != "" {
+				principal.Roles = strings.Split(roles, ",")
+			}
+			if permissions := r.Header.Get("X-Permissions"); permissions != "" {
+				principal.Permissions = strings.Split(permissions, ",")
+			}
+			next.ServeHTTP(w, withPrincipal(r, principal))
+		})
+	})
+	r.Use(AuthorizeMiddleware(RolePermissions(map[string][]string{
+		"admin": {"admin", "users:delete", "orders:read"},
+		"clerk": {"orders:read"},
+	})))
+
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}
+	r.Get("/health", ok)
+	r.Require("orders:read").Get("/orders", ok)
+	r.Require("orders:write").Meta("audit", true).Post("/orders", ok)
+
+	admin := r.Prefix("/admin").Require("admin").Meta("owner", "platform").SubRouter()
+	admin.Get("/users", ok)
+	admin.(*SubRouter).Require("users:delete").Delete("/users", ok)
+	admin.With(func(next http.Handler) http.Handler { return next }).Get("/stats", ok)
+
+	r.Mount("/static", http.HandlerFunc(ok))
+	admin.(*SubRouter).Mount("/reports", http.HandlerFunc(ok))
+	r.Require("orders:read").Mount("/exports", http.HandlerFunc(ok))
+
+	return r
+}
+
+func TestAuthorizeMiddleware(t *testing.T) {
+	r := createAuthzTestRouter()
+
+	type Test struct {
+		method      string
+		url         string
+		subject     string
+		roles       string
+		permissions string
+		status      int
+	}
+
+	testTable := []Test{
+		{method: "GET", url: "/health", status: http.StatusOK},
+		{method: "GET", url: "/orders", status: http.StatusUnauthorized},
+		{method: "GET", url: "/orders", subject: "bob", status: http.StatusForbidden},
+		{method: "GET", url: "/orders", subject: "bob", roles: "clerk", status: http.StatusOK},
+		{method: "GET", url: "/orders", subject: "bob", permissions: "orders:read", status: http.StatusOK},
+		{method: "POST", url: "/orders", subject: "bob", roles: "clerk,admin", status: http.StatusForbidden},
+		{method: "POST", url: "/orders", subject: "bob", roles: "clerk", permissions: "orders:write", status: http.StatusOK},
+		{method: "GET", url: "/admin/users", subject: "alice", roles: "clerk", status: http.StatusForbidden},
+		{method: "GET", url: "/admin/users", subject: "alice", roles: "admin", status: http.StatusOK},
+		{method: "GET", url: "/admin/stats", subject: "alice", roles: "clerk", status: http.StatusForbidden},
+		{method: "DELETE", url: "/admin/users", subject: "alice", permissions: "admin", status: http.StatusForbidden},
+		{method: "DELETE", url: "/admin/users", subject: "alice", roles: "admin", status: http.StatusOK},
+		{method: "GET", url: "/static/app.js", status: http.StatusOK},
+		{method: "GET", url: "/admin/reports/daily", subject: "alice", roles: "clerk", status: http.StatusForbidden},
+		{method: "GET", url: "/admin/reports/daily", subject: "alice", roles: "admin", status: http.StatusOK},
+		{method: "GET", url: "/exports/orders.csv", status: http.StatusUnauthorized},
+		{method: "GET", url: "/exports/orders.csv", subject: "bob", roles: "clerk", status: http.StatusOK},
+	}
+
+	for i, test := range testTable {
+		req := httptest.NewRequest(test.method, test.url, nil)
+		if test.subject != "" {
+			req.Header.Set("X-Subject", test.subject)
+		}
+		if test.roles != "" {
+			req.Header.Set("X-Roles", test.roles)
+		}
+		if test.permissions != "" {
+			req.Header.Set("X-Permissions", test.permissions)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != test.status {
+			t.Errorf("Failed test %d, expected %d for %s %s but got %d", i, test.status, test.method, test.url, w.Code)
+		}
+	}
+}
+
+func TestWalkRoutes(t *testing.T) {
+	r := createAuthzTestRouter()
+
+	var routes []RouteInfo
+	r.Walk(func(route RouteInfo) error {
+		routes = append(routes, route)
+		return nil
+	})
+
+	expected := []RouteInfo{
+		{Method: "GET", Pattern: "/health", Permissions: []string{}, Meta: map[string]any{}},
+		{Method: "GET", Pattern: "/orders", Permissions: []string{"orders:read"}, Meta: map[string]any{}},
+		{Method: "GET", Pattern: "/admin/users", Permissions: []string{"admin"}, Meta: map[string]any{"owner": "platform"}},
+		{Method: "GET", Pattern: "/admin/stats", Permissions: []string{"admin"}, Meta: map[string]any{"owner": "platform"}},
+		{Method: "POST", Pattern: "/orders", Permissions: []string{"orders:write"}, Meta: map[string]any{"audit": true}},
+		{Method: "DELETE", Pattern: "/admin/users", Permissions: []string{"admin", "users:delete"}, Meta: map[string]any{"owner": "platform"}},
+		{Method: "*", Pattern: "/static", Permissions: []string{}, Meta: map[string]any{}},
+		{Method: "*", Pattern: "/admin/reports", Permissions: []string{"admin"}, Meta: map[string]any{"owner": "platform"}},
+		{Method: "*", Pattern: "/exports", Permissions: []string{"orders:read"}, Meta: map[string]any{}},
+	}
+	if !reflect.DeepEqual(routes, expected) {
+		t.Errorf("Expected routes %v but got %v", expected, routes)
+	}
+
+	unprotected := r.UnprotectedRoutes()
+	if !reflect.DeepEqual(unprotected, []string{"GET /health", "* /static"}) {
+		t.Errorf("Expected only GET /health and the static mount to be unprotected but got %v", unprotected)
+	}
+}
@@ -0,0 +1,138 @@
+package httprouter
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+)
+
+type BodyOptions struct {
+	// MaxBytes limits the size of the body as sent, there is no limit if it is 0
+	MaxBytes int64
+
+	// MaxDecodedBytes limits the size of a compressed body after it is decoded, defaults to MaxBytes
+	MaxDecodedBytes int64
+
+	// DisableDecoding leaves Content-Encoding request bodies for the handler to decode
+	DisableDecoding bool
+}
+
+func BodyLimitMiddleware(maxBytes int64) Middleware {
+	return BodyMiddleware(BodyOptions{MaxBytes: maxBytes})
+}
+
+// BodyMiddleware limits request body sizes and decodes gzip and deflate request bodies. Handlers reading past a
+// limit get an *http.MaxBytesError which the router's error handling renders as a 413. The next handler is given a
+// copy of the request so the caller's request keeps its original body and headers
+func BodyMiddleware(opts BodyOptions) Middleware {
+	if opts.MaxDecodedBytes <= 0 {
+		opts.MaxDecodedBytes = opts.MaxBytes
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// the body and headers are swapped on a copy so the caller's request is left as it was
+			req := *r
+			r = &req
+			r.Header = r.Header.Clone()
+			if opts.MaxBytes > 0 {
+				if r.ContentLength > opts.MaxBytes {
+					HandleError(w, r, &http.MaxBytesError{Limit: opts.MaxBytes})
+					return
+				}
+				r.Body = http.MaxBytesReader(w, r.Body, opts.MaxBytes)
+			}
+
+			encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+			if opts.DisableDecoding || encoding == "" || encoding == "identity" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := decodeRequestBody(r.Body, encoding)
+			if err != nil {
+				HandleError(w, r, err)
+				return
+			}
+			if opts.MaxDecodedBytes > 0 {
+				body = &decodedBodyLimiter{ReadCloser: body, remaining: opts.MaxDecodedBytes, limit: opts.MaxDecodedBytes}
+			}
+
+			r.Body = body
+			r.Header.Del("Content-Encoding")
+			r.Header.Del("Content-Length")
+			r.ContentLength = -1
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+type decodedBody struct {
+	io.Reader
+	decoder io.Closer
+	body    io.Closer
+}
+
+func (db *decodedBody) Close() error {
+	db.decoder.Close()
+	return db.body.Close()
+}
+
+func decodeRequestBody(body io.ReadCloser, encoding string) (io.ReadCloser, error) {
+	switch encoding {
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, &HTTPError{Status: http.StatusBadRequest, Message: "invalid gzip request body", Err: err}
+		}
+		return &decodedBody{Reader: gz, decoder: gz, body: body}, nil
+	case "deflate":
+		// deflate should be zlib wrapped but some clients send raw deflate data
+		br := bufio.NewReader(body)
+		header, err := br.Peek(2)
+		if err == nil && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 && header[0]&0x0f == 8 {
+			zr, err := zlib.NewReader(br)
+			if err != nil {
+				return nil, &HTTPError{Status: http.StatusBadRequest, Message: "invalid deflate request body", Err: err}
+			}
+			return &decodedBody{Reader: zr, decoder: zr, body: body}, nil
+		}
+		fr := flate.NewReader(br)
+		return &decodedBody{Reader: fr, decoder: fr, body: body}, nil
+	default:
+		return nil, &HTTPError{Status: http.StatusUnsupportedMediaType, Message: "unsupported content encoding " + encoding}
+	}
+}
+
+// guards against decompression bombs by limiting the decoded size the same way http.MaxBytesReader limits the body
+type decodedBodyLimiter struct {
+	io.ReadCloser
+	remaining int64
+	limit     int64
+}
+
+func (l *decodedBodyLimiter) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		// read one more byte to tell a body that ends exactly at the limit apart from one that is too large
+		var b [1]byte
+		if n, _ := l.ReadCloser.Read(b[:]); n == 0 {
+			return 0, io.EOF
+		}
+		return 0, &http.MaxBytesError{Limit: l.limit}
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.ReadCloser.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
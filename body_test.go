@@ -0,0 +1,117 @@
+package httprouter
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func encodeBody(t *testing.T, encoding string, body string) []byte {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "raw-deflate":
+		w, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	default:
+		return []byte(body)
+	}
+	if _, err := w.Write([]byte(body)); err != nil {
+		t.Fatalf("Failed to encode body: %v", err)
+	}
+	w.Close()
+	return buf.Bytes()
+}
+
+func TestBodyMiddleware(t *testing.T) {
+	r := NewRouter()
+	r.Use(BodyMiddleware(BodyOptions{MaxBytes: 1024, MaxDecodedBytes: 4096}))
+	r.RouteE("POST", "/echo", func(w http.ResponseWriter, r *http.Request) error {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		if r.Header.Get("Content-Encoding") != "" {
+			w.Write([]byte("still encoded "))
+		}
+		w.Write(b)
+		return nil
+	})
+
+	bomb := strings.Repeat("0", 1<<16)
+
+	type Test struct {
+		encoding      string
+		header        string
+		body          []byte
+		contentLength int64
+		status        int
+		bodyOut       string
+	}
+
+	testTable := []Test{
+		{body: []byte("Hello World"), status: http.StatusOK, bodyOut: "Hello World"},
+		{header: "gzip", body: encodeBody(t, "gzip", "Hello Gzip"), status: http.StatusOK, bodyOut: "Hello Gzip"},
+		{header: "deflate", body: encodeBody(t, "deflate", "Hello Zlib"), status: http.StatusOK, bodyOut: "Hello Zlib"},
+		{header: "deflate", body: encodeBody(t, "raw-deflate", "Hello Deflate"), status: http.StatusOK, bodyOut: "Hello Deflate"},
+		{header: "gzip", body: encodeBody(t, "gzip", strings.Repeat("a", 4096)), status: http.StatusOK, bodyOut: strings.Repeat("a", 4096)},
+		{body: []byte(strings.Repeat("a", 2048)), status: http.StatusRequestEntityTooLarge, bodyOut: "413 request entity too large"},
+		{body: []byte(strings.Repeat("a", 2048)), contentLength: -1, status: http.StatusRequestEntityTooLarge, bodyOut: "413 request entity too large"},
+		{header: "gzip", body: encodeBody(t, "gzip", bomb), status: http.StatusRequestEntityTooLarge, bodyOut: "413 request entity too large"},
+		{header: "gzip", body: []byte("not gzip"), status: http.StatusBadRequest, bodyOut: "400 invalid gzip request body"},
+		{header: "br", body: []byte("brotli"), status: http.StatusUnsupportedMediaType, bodyOut: "415 unsupported content encoding br"},
+	}
+
+	for i, test := range testTable {
+		req := httptest.NewRequest("POST", "/echo", bytes.NewReader(test.body))
+		if test.header != "" {
+			req.Header.Set("Content-Encoding", test.header)
+		}
+		if test.contentLength != 0 {
+			req.ContentLength = test.contentLength
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != test.status || w.Body.String() != test.bodyOut {
+			body := w.Body.String()
+			if len(body) > 64 {
+				body = body[:64] + "..."
+			}
+			t.Errorf("Failed test %d, expected %d %.64q, got %d %q", i, test.status, test.bodyOut, w.Code, body)
+		}
+	}
+}
+
+func TestBodyMiddlewareKeepsRequest(t *testing.T) {
+	handler := BodyMiddleware(BodyOptions{MaxBytes: 1024})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(w, r.Body)
+	}))
+
+	body := io.NopCloser(bytes.NewReader(encodeBody(t, "gzip", "Hello Gzip")))
+	req := httptest.NewRequest("POST", "/echo", body)
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("Content-Length", "30")
+	req.ContentLength = 30
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Body.String() != "Hello Gzip" {
+		t.Errorf("Expected the decoded body but got %q", w.Body.String())
+	}
+	if req.Body != body || req.ContentLength != 30 {
+		t.Errorf("Expected the caller's body and content length to be kept but got %T %d", req.Body, req.ContentLength)
+	}
+	if req.Header.Get("Content-Encoding") != "gzip" || req.Header.Get("Content-Length") != "30" {
+		t.Errorf("Expected the caller's headers to be kept but got %v", req.Header)
+	}
+}
@@ -5,6 +5,7 @@ import "net/http"
 type SubRouter struct {
 	prefix      string
 	middlewares []Middleware
+	meta        routeMeta
 	parent      Router
 }
 
@@ -24,11 +25,23 @@ func (router *SubRouter) Prefix(p string) SubRouterBuilder {
 
 func (router *SubRouter) With(m Middleware) RouteBuilder {
 	return RouteBuilder{
-		middleware: m,
-		router:     router,
+		middlewares: []Middleware{m},
+		router:      router,
 	}
 }
 
+func (router *SubRouter) Require(permissions ...string) RouteBuilder {
+	return RouteBuilder{router: router}.Require(permissions...)
+}
+
+func (router *SubRouter) Meta(key string, value any) RouteBuilder {
+	return RouteBuilder{router: router}.Meta(key, value)
+}
+
+func (router *SubRouter) Name(name string) RouteBuilder {
+	return RouteBuilder{router: router}.Name(name)
+}
+
 func (router *SubRouter) Get(route string, routeHandler http.HandlerFunc) {
 	router.Route("GET", route, routeHandler)
 }
@@ -46,9 +59,29 @@ func (router *SubRouter) Delete(route string, routeHandler http.HandlerFunc) {
 }
 
 func (router *SubRouter) Route(method string, route string, routeHandler http.HandlerFunc) {
+	router.handle(method, route, routeHandler, nil, routeMeta{})
+}
+
+func (router *SubRouter) handle(method string, route string, routeHandler http.HandlerFunc, middlewares []Middleware, meta routeMeta) {
 	route = router.prefix + route
-	routeHandler = buildHandler(routeHandler, router.middlewares...).ServeHTTP
-	router.parent.Route(method, route, routeHandler)
+	middlewares = append(append([]Middleware{}, router.middlewares...), middlewares...)
+	meta = router.meta.merge(meta)
+	meta.prefix = router.prefix + meta.prefix
+	registerRoute(router.parent, method, route, routeHandler, middlewares, meta)
+}
+
+// Mount delegates the requests under the sub router's prefix and prefix that no route matches to handler, like ServerRouter.Mount
+func (router *SubRouter) Mount(prefix string, handler http.Handler) {
+	router.mount(prefix, handler, nil, routeMeta{})
+}
+
+func (router *SubRouter) mount(prefix string, handler http.Handler, middlewares []Middleware, meta routeMeta) {
+	middlewares = append(append([]Middleware{}, router.middlewares...), middlewares...)
+	mountHandler(router.parent, router.prefix+prefix, handler, middlewares, router.meta.merge(meta))
+}
+
+func (router *SubRouter) RouteE(method string, route string, routeHandler HandlerFuncE) {
+	router.Route(method, route, HandleE(routeHandler))
 }
 
 func (router *SubRouter) Use(middleware Middleware) {
@@ -62,11 +95,13 @@ type RouterBuilder struct {
 type SubRouterBuilder struct {
 	parent Router
 	prefix string
+	meta   routeMeta
 }
 
 type RouteBuilder struct {
-	middleware Middleware
-	router     Router
+	middlewares []Middleware
+	meta        routeMeta
+	router      Router
 }
 
 func Prefix(p string) RouterBuilder {
@@ -77,38 +112,104 @@ func Prefix(p string) RouterBuilder {
 
 func (rb RouterBuilder) NewRouter() *ServerRouter {
 	return &ServerRouter{
-		prefix:          rb.prefix,
-		middlewares:     []Middleware{},
-		trie:            newTrie[http.Handler](),
-		notFoundHandler: notFound,
+		prefix:        rb.prefix,
+		middlewares:   []Middleware{},
+		trie:          newTrie[routeEntry](),
+		mounts:        newTrie[routeEntry](),
+		errorRenderer: TextErrorRenderer,
 	}
 }
 
+// Require adds permissions that every route of the sub router requires
+func (rb SubRouterBuilder) Require(permissions ...string) SubRouterBuilder {
+	rb.meta = rb.meta.merge(routeMeta{permissions: permissions})
+	return rb
+}
+
+// Meta attaches a value to every route of the sub router
+func (rb SubRouterBuilder) Meta(key string, value any) SubRouterBuilder {
+	rb.meta = rb.meta.merge(routeMeta{values: map[string]any{key: value}})
+	return rb
+}
+
 func (rb SubRouterBuilder) SubRouter() Router {
 	return &SubRouter{
 		prefix:      rb.prefix,
 		parent:      rb.parent,
 		middlewares: []Middleware{},
+		meta:        rb.meta,
 	}
 }
 
+func (rb RouteBuilder) With(m Middleware) RouteBuilder {
+	rb.middlewares = append(append([]Middleware{}, rb.middlewares...), m)
+	return rb
+}
+
+// Require adds permissions that the route requires, they are enforced by AuthorizeMiddleware
+func (rb RouteBuilder) Require(permissions ...string) RouteBuilder {
+	rb.meta = rb.meta.merge(routeMeta{permissions: permissions})
+	return rb
+}
+
+// Meta attaches a value to the route that middlewares can read once it has been matched
+func (rb RouteBuilder) Meta(key string, value any) RouteBuilder {
+	rb.meta = rb.meta.merge(routeMeta{values: map[string]any{key: value}})
+	return rb
+}
+
+// Name identifies the route in CurrentRoute and Walk
+func (rb RouteBuilder) Name(name string) RouteBuilder {
+	rb.meta.name = name
+	return rb
+}
+
 func (rb RouteBuilder) Get(route string, routeHandler http.HandlerFunc) {
-	rb.router.Route("GET", route, routeHandler)
+	rb.Route("GET", route, routeHandler)
 }
 
 func (rb RouteBuilder) Post(route string, routeHandler http.HandlerFunc) {
-	rb.router.Route("POST", route, routeHandler)
+	rb.Route("POST", route, routeHandler)
 }
 
 func (rb RouteBuilder) Put(route string, routeHandler http.HandlerFunc) {
-	rb.router.Route("PUT", route, routeHandler)
+	rb.Route("PUT", route, routeHandler)
 }
 
 func (rb RouteBuilder) Delete(route string, routeHandler http.HandlerFunc) {
-	rb.router.Route("DELETE", route, routeHandler)
+	rb.Route("DELETE", route, routeHandler)
 }
 
 func (rb RouteBuilder) Route(method string, route string, routeHandler http.HandlerFunc) {
-	routeHandler = buildHandler(routeHandler, rb.middleware).ServeHTTP
-	rb.router.Route(method, route, routeHandler)
+	registerRoute(rb.router, method, route, routeHandler, rb.middlewares, rb.meta)
+}
+
+// routers implemented outside this package only get the handler wrapped in the middlewares. The metadata is dropped,
+// except for permissions which would leave the route unprotected
+func registerRoute(router Router, method string, route string, routeHandler http.HandlerFunc, middlewares []Middleware, meta routeMeta) {
+	if registrar, ok := router.(routeRegistrar); ok {
+		registrar.handle(method, route, routeHandler, middlewares, meta)
+		return
+	}
+	if len(meta.permissions) > 0 {
+		panic("httprouter: permissions can't be required for routes registered through a router from another package")
+	}
+	router.Route(method, route, buildHandler(routeHandler, middlewares...).ServeHTTP)
+}
+
+// Mount mounts handler with the builder's middlewares and metadata, like ServerRouter.Mount
+func (rb RouteBuilder) Mount(prefix string, handler http.Handler) {
+	mountHandler(rb.router, prefix, handler, rb.middlewares, rb.meta)
+}
+
+func mountHandler(router Router, prefix string, handler http.Handler, middlewares []Middleware, meta routeMeta) {
+	mounter, ok := router.(routeMounter)
+	if !ok {
+		panic("httprouter: handlers can't be mounted on a router from another package")
+	}
+	mounter.mount(prefix, handler, middlewares, meta)
+}
+
+func (rb RouteBuilder) RouteE(method string, route string, routeHandler HandlerFuncE) {
+	rb.Route(method, route, HandleE(routeHandler))
 }
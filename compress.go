@@ -0,0 +1,312 @@
+package httprouter
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Encoder adds a content coding to the compression middleware. Closing the returned writer must flush any
+// remaining output and may release the writer for reuse
+type Encoder interface {
+	Encoding() string
+	NewWriter(w io.Writer) io.WriteCloser
+}
+
+type resetWriter interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+type pooledEncoder struct {
+	encoding string
+	pool     sync.Pool
+}
+
+type pooledWriter struct {
+	resetWriter
+	encoder *pooledEncoder
+}
+
+func (pw *pooledWriter) Close() error {
+	err := pw.resetWriter.Close()
+	pw.resetWriter.Reset(nil)
+	pw.encoder.pool.Put(pw.resetWriter)
+	return err
+}
+
+func (e *pooledEncoder) Encoding() string {
+	return e.encoding
+}
+
+func (e *pooledEncoder) NewWriter(w io.Writer) io.WriteCloser {
+	rw := e.pool.Get().(resetWriter)
+	rw.Reset(w)
+	return &pooledWriter{resetWriter: rw, encoder: e}
+}
+
+func GzipEncoder(level int) Encoder {
+	if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		level = gzip.DefaultCompression
+	}
+	e := &pooledEncoder{encoding: "gzip"}
+	e.pool.New = func() any {
+		w, _ := gzip.NewWriterLevel(nil, level)
+		return w
+	}
+	return e
+}
+
+func DeflateEncoder(level int) Encoder {
+	if level < flate.HuffmanOnly || level > flate.BestCompression {
+		level = flate.DefaultCompression
+	}
+	e := &pooledEncoder{encoding: "deflate"}
+	e.pool.New = func() any {
+		w, _ := flate.NewWriter(nil, level)
+		return w
+	}
+	return e
+}
+
+type CompressOptions struct {
+	// Encoders are listed in order of preference when the client accepts several equally, defaults to gzip and deflate
+	Encoders []Encoder
+
+	// MinSize is the smallest response in bytes that is compressed, defaults to 1024
+	MinSize int
+
+	// ContentTypes are the compressible media types, a trailing "/" matches a whole type such as "text/"
+	ContentTypes []string
+}
+
+var defaultCompressibleTypes = []string{
+	"text/",
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	"application/wasm",
+	"image/svg+xml",
+}
+
+func CompressMiddleware(opts CompressOptions) Middleware {
+	if len(opts.Encoders) == 0 {
+		opts.Encoders = []Encoder{GzipEncoder(gzip.DefaultCompression), DeflateEncoder(flate.DefaultCompression)}
+	}
+	if opts.MinSize <= 0 {
+		opts.MinSize = 1024
+	}
+	if len(opts.ContentTypes) == 0 {
+		opts.ContentTypes = defaultCompressibleTypes
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			// browsers send Accept-Encoding with WebSocket handshakes, whose connection is hijacked rather than compressed
+			encoder := negotiateEncoder(r.Header.Get("Accept-Encoding"), opts.Encoders)
+			if encoder == nil || r.Method == "HEAD" || r.Header.Get("Upgrade") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{ResponseWriter: WrapResponseWriter(w), encoder: encoder, opts: &opts, status: http.StatusOK}
+			defer cw.close()
+			hijacker, _ := cw.ResponseWriter.(http.Hijacker)
+			pusher, _ := cw.ResponseWriter.(http.Pusher)
+			next.ServeHTTP(exposeInterfaces(cw, w, writerInterfaces{
+				flusher:    (*compressFlusher)(cw),
+				hijacker:   hijacker,
+				readerFrom: (*compressReaderFrom)(cw),
+				pusher:     pusher,
+			}), r)
+		})
+	}
+}
+
+// chooses the encoder with the highest q-value in the Accept-Encoding header, ties go to the earliest encoder
+func negotiateEncoder(accept string, encoders []Encoder) Encoder {
+	if accept == "" {
+		return nil
+	}
+	qvalues := make(map[string]float64)
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(fields[0]))
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		qvalues[coding] = q
+	}
+
+	var best Encoder
+	bestQ := 0.0
+	for _, encoder := range encoders {
+		q, ok := qvalues[encoder.Encoding()]
+		if !ok {
+			q, ok = qvalues["*"]
+		}
+		if ok && q > bestQ {
+			best = encoder
+			bestQ = q
+		}
+	}
+	return best
+}
+
+type compressWriter struct {
+	ResponseWriter
+	encoder Encoder
+	opts    *CompressOptions
+
+	status      int
+	wroteHeader bool
+	decided     bool
+	buf         bytes.Buffer
+	enc         io.WriteCloser
+}
+
+func (cw *compressWriter) Header() http.Header {
+	return cw.ResponseWriter.Header()
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.wroteHeader || cw.decided {
+		return
+	}
+	if status >= 100 && status < 200 && status != http.StatusSwitchingProtocols {
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	cw.wroteHeader = true
+	cw.status = status
+	if !bodyAllowed(status) {
+		cw.decide(false)
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	cw.wroteHeader = true
+	if cw.decided {
+		if cw.enc != nil {
+			return cw.enc.Write(b)
+		}
+		return cw.ResponseWriter.Write(b)
+	}
+
+	n, _ := cw.buf.Write(b)
+	if cw.buf.Len() >= cw.opts.MinSize {
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+func bodyAllowed(status int) bool {
+	return status != http.StatusNoContent && status != http.StatusNotModified
+}
+
+func (cw *compressWriter) compressible() bool {
+	header := cw.ResponseWriter.Header()
+	if header.Get("Content-Encoding") != "" || !bodyAllowed(cw.status) {
+		return false
+	}
+	if length := header.Get("Content-Length"); length != "" {
+		if n, err := strconv.Atoi(length); err == nil && n < cw.opts.MinSize {
+			return false
+		}
+	}
+
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(cw.buf.Bytes())
+		header.Set("Content-Type", contentType)
+	}
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	if strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml") {
+		return true
+	}
+	for _, t := range cw.opts.ContentTypes {
+		if mediaType == t || (strings.HasSuffix(t, "/") && strings.HasPrefix(mediaType, t)) {
+			return true
+		}
+	}
+	return false
+}
+
+// decides whether to compress once enough of the response is known, then writes the header and any buffered bytes
+func (cw *compressWriter) decide(largeEnough bool) error {
+	cw.decided = true
+	if largeEnough && cw.compressible() {
+		header := cw.ResponseWriter.Header()
+		header.Set("Content-Encoding", cw.encoder.Encoding())
+		header.Del("Content-Length")
+		cw.enc = cw.encoder.NewWriter(cw.ResponseWriter)
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	if cw.buf.Len() == 0 {
+		return nil
+	}
+	var err error
+	if cw.enc != nil {
+		_, err = cw.enc.Write(cw.buf.Bytes())
+	} else {
+		_, err = cw.ResponseWriter.Write(cw.buf.Bytes())
+	}
+	cw.buf.Reset()
+	return err
+}
+
+type compressFlusher compressWriter
+
+func (f *compressFlusher) Flush() {
+	cw := (*compressWriter)(f)
+	if !cw.decided {
+		// a flushed response is streamed so it is compressed regardless of its size so far
+		cw.decide(true)
+	}
+	if flusher, ok := cw.enc.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+type compressReaderFrom compressWriter
+
+func (rf *compressReaderFrom) ReadFrom(src io.Reader) (int64, error) {
+	cw := (*compressWriter)(rf)
+	if cw.decided && cw.enc == nil {
+		// responses that aren't compressed keep the connection's ReadFrom, which may use sendfile
+		return cw.ResponseWriter.(io.ReaderFrom).ReadFrom(src)
+	}
+	return io.Copy(cw, src)
+}
+
+func (cw *compressWriter) close() {
+	if !cw.decided {
+		if !cw.wroteHeader {
+			return
+		}
+		cw.decide(false)
+	}
+	if cw.enc != nil {
+		cw.enc.Close()
+	}
+}
@@ -0,0 +1,269 @@
+package httprouter
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type upperEncoder struct{}
+
+type upperWriter struct {
+	w io.Writer
+}
+
+func (e upperEncoder) Encoding() string {
+	return "x-upper"
+}
+
+func (e upperEncoder) NewWriter(w io.Writer) io.WriteCloser {
+	return upperWriter{w: w}
+}
+
+func (uw upperWriter) Write(b []byte) (int, error) {
+	return uw.w.Write(bytes.ToUpper(b))
+}
+
+func (uw upperWriter) Close() error {
+	return nil
+}
+
+func decodeBody(t *testing.T, encoding string, body []byte) string {
+	var reader io.Reader
+	switch encoding {
+	case "gzip":
+		gz, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("Failed to read gzip body: %v", err)
+		}
+		reader = gz
+	case "deflate":
+		reader = flate.NewReader(bytes.NewReader(body))
+	default:
+		return string(body)
+	}
+	b, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Failed to decode %s body: %v", encoding, err)
+	}
+	return string(b)
+}
+
+func TestCompressMiddleware(t *testing.T) {
+	large := strings.Repeat(`{"name":"product"},`, 100)
+	small := `{"name":"product"}`
+
+	r := NewRouter()
+	r.Use(CompressMiddleware(CompressOptions{}))
+	r.Get("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Length", strconv.Itoa(len(large)))
+		w.Write([]byte(large))
+	})
+	r.Get("/small", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(small))
+	})
+	r.Get("/sniffed", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html>" + large + "</html>"))
+	})
+	r.Get("/image", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte(large))
+	})
+	r.Get("/encoded", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "br")
+		w.Write([]byte(large))
+	})
+	r.Get("/nocontent", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	r.Get("/notmodified", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotModified)
+	})
+	r.Route("HEAD", "/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Length", strconv.Itoa(len(large)))
+	})
+
+	type Test struct {
+		method   string
+		url      string
+		accept   string
+		status   int
+		encoding string
+		bodyOut  string
+	}
+
+	testTable := []Test{
+		{method: "GET", url: "/large", accept: "gzip, deflate", status: http.StatusOK, encoding: "gzip", bodyOut: large},
+		{method: "GET", url: "/large", accept: "gzip;q=0.5, deflate", status: http.StatusOK, encoding: "deflate", bodyOut: large},
+		{method: "GET", url: "/large", accept: "*", status: http.StatusOK, encoding: "gzip", bodyOut: large},
+		{method: "GET", url: "/large", accept: "gzip;q=0, br", status: http.StatusOK, encoding: "", bodyOut: large},
+		{method: "GET", url: "/large", accept: "", status: http.StatusOK, encoding: "", bodyOut: large},
+		{method: "GET", url: "/small", accept: "gzip", status: http.StatusOK, encoding: "", bodyOut: small},
+		{method: "GET", url: "/sniffed", accept: "gzip", status: http.StatusOK, encoding: "gzip", bodyOut: "<html>" + large + "</html>"},
+		{method: "GET", url: "/image", accept: "gzip", status: http.StatusOK, encoding: "", bodyOut: large},
+		{method: "GET", url: "/encoded", accept: "gzip", status: http.StatusOK, encoding: "br", bodyOut: large},
+		{method: "GET", url: "/nocontent", accept: "gzip", status: http.StatusNoContent, encoding: "", bodyOut: ""},
+		{method: "GET", url: "/notmodified", accept: "gzip", status: http.StatusNotModified, encoding: "", bodyOut: ""},
+		{method: "HEAD", url: "/large", accept: "gzip", status: http.StatusOK, encoding: "", bodyOut: ""},
+	}
+
+	for i, test := range testTable {
+		req := httptest.NewRequest(test.method, test.url, nil)
+		if test.accept != "" {
+			req.Header.Set("Accept-Encoding", test.accept)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		encoding := w.Header().Get("Content-Encoding")
+		if w.Code != test.status || encoding != test.encoding {
+			t.Errorf("Failed test %d, expected %d with encoding %q but got %d with %q", i, test.status, test.encoding, w.Code, encoding)
+			continue
+		}
+		if body := decodeBody(t, encoding, w.Body.Bytes()); body != test.bodyOut {
+			t.Errorf("Failed test %d, expected body %q but got %q", i, test.bodyOut, body)
+		}
+		if w.Header().Get("Vary") != "Accept-Encoding" {
+			t.Errorf("Failed test %d, expected Vary Accept-Encoding but got %q", i, w.Header().Get("Vary"))
+		}
+		if (encoding == "gzip" || encoding == "deflate") && w.Header().Get("Content-Length") != "" {
+			t.Errorf("Failed test %d, expected Content-Length to be removed from a compressed response", i)
+		}
+	}
+}
+
+func TestCompressMiddlewareStreaming(t *testing.T) {
+	flushed := make(chan struct{})
+	server := httptest.NewServer(CompressMiddleware(CompressOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: first\n\n"))
+		w.(http.Flusher).Flush()
+		<-flushed
+		w.Write([]byte("data: second\n\n"))
+	})))
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Failed to send GET request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.Header.Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Expected a streamed response to be compressed but got %q", resp.Header.Get("Content-Encoding"))
+	}
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read gzip body: %v", err)
+	}
+
+	first := make([]byte, len("data: first\n\n"))
+	if _, err := io.ReadFull(gz, first); err != nil || string(first) != "data: first\n\n" {
+		t.Fatalf("Expected the first event to be flushed before the handler finished but got %q %v", first, err)
+	}
+	close(flushed)
+
+	rest, _ := io.ReadAll(gz)
+	if string(rest) != "data: second\n\n" {
+		t.Errorf("Expected the second event but got %q", rest)
+	}
+}
+
+func TestCompressMiddlewareCustomEncoder(t *testing.T) {
+	handler := CompressMiddleware(CompressOptions{Encoders: []Encoder{upperEncoder{}}, MinSize: 1})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("hello world"))
+		}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip, x-upper")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Header().Get("Content-Encoding") != "x-upper" || w.Body.String() != "HELLO WORLD" {
+		t.Errorf("Expected the custom encoder to be used but got %q %q", w.Header().Get("Content-Encoding"), w.Body.String())
+	}
+}
+
+func TestCompressMiddlewareInterfaces(t *testing.T) {
+	type Test struct {
+		w          http.ResponseWriter
+		flusher    bool
+		hijacker   bool
+		readerFrom bool
+		pusher     bool
+	}
+
+	testTable := []Test{
+		{w: plainWriter{httptest.NewRecorder()}},
+		{w: httptest.NewRecorder(), flusher: true},
+		{w: hijackWriter{httptest.NewRecorder()}, flusher: true, hijacker: true},
+		{w: fullWriter{httptest.NewRecorder()}, flusher: true, hijacker: true, readerFrom: true, pusher: true},
+	}
+
+	for i, test := range testTable {
+		var flusher, hijacker, readerFrom, pusher bool
+		handler := CompressMiddleware(CompressOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, flusher = w.(http.Flusher)
+			_, hijacker = w.(http.Hijacker)
+			_, readerFrom = w.(io.ReaderFrom)
+			_, pusher = w.(http.Pusher)
+		}))
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		handler.ServeHTTP(test.w, req)
+
+		if flusher != test.flusher || hijacker != test.hijacker || readerFrom != test.readerFrom || pusher != test.pusher {
+			t.Errorf("Failed test %d, expected interfaces %v %v %v %v, got %v %v %v %v", i,
+				test.flusher, test.hijacker, test.readerFrom, test.pusher, flusher, hijacker, readerFrom, pusher)
+		}
+	}
+
+	body := strings.Repeat("compressible text ", 200)
+	handler := CompressMiddleware(CompressOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.(io.ReaderFrom).ReadFrom(strings.NewReader(body))
+	}))
+
+	// a body copied with ReadFrom is still compressed, but an upgrade request is left alone
+	for _, upgrade := range []string{"", "websocket"} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		if upgrade != "" {
+			req.Header.Set("Connection", "Upgrade")
+			req.Header.Set("Upgrade", upgrade)
+		}
+		handler.ServeHTTP(fullWriter{rec}, req)
+
+		encoding := rec.Header().Get("Content-Encoding")
+		if (upgrade == "") != (encoding == "gzip") {
+			t.Errorf("Expected compression only without an upgrade, got %q for upgrade %q", encoding, upgrade)
+		}
+		if upgrade == "" {
+			gz, err := gzip.NewReader(rec.Body)
+			if err != nil {
+				t.Fatalf("Failed to read gzip body: %v", err)
+			}
+			if decoded, _ := io.ReadAll(gz); string(decoded) != body {
+				t.Errorf("Expected the body to round trip but got %d bytes", len(decoded))
+			}
+		} else if rec.Body.String() != body {
+			t.Errorf("Expected the upgrade response to be sent as is")
+		}
+	}
+}
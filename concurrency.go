@@ -0,0 +1,171 @@
+package httprouter
+
+import (
+	"container/list"
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Priority int
+
+const (
+	PriorityNormal Priority = iota
+	// PriorityHigh requests are let through before any queued normal requests
+	PriorityHigh
+	// PriorityBypass requests are never limited or counted
+	PriorityBypass
+)
+
+type ConcurrencyOptions struct {
+	// MaxInFlight is how many requests may be served at once, it is required
+	MaxInFlight int
+
+	// MaxQueue is how many requests may wait for a slot, requests beyond it are shed immediately
+	MaxQueue int
+
+	// MaxWait is how long a queued request waits before it is shed, it waits until the request is canceled if 0
+	MaxWait time.Duration
+
+	// RetryAfter is sent with shed requests, defaults to 1 second
+	RetryAfter time.Duration
+
+	// Classify chooses the priority of a request, all requests are PriorityNormal if nil and unknown priorities are
+	// treated as PriorityNormal
+	Classify func(r *http.Request) Priority
+}
+
+type ConcurrencyLimiter struct {
+	opts     ConcurrencyOptions
+	mu       sync.Mutex
+	inFlight int
+	queues   [2]*list.List
+}
+
+func NewConcurrencyLimiter(opts ConcurrencyOptions) *ConcurrencyLimiter {
+	if opts.MaxInFlight <= 0 {
+		panic("httprouter: concurrency limiter requires a positive MaxInFlight")
+	}
+	if opts.RetryAfter <= 0 {
+		opts.RetryAfter = time.Second
+	}
+	return &ConcurrencyLimiter{
+		opts:   opts,
+		queues: [2]*list.List{list.New(), list.New()},
+	}
+}
+
+// BypassPrefixes classifies requests for routes with any of the prefixes, such as those of an admin or health check
+// subrouter, as PriorityBypass
+func BypassPrefixes(prefixes ...string) func(r *http.Request) Priority {
+	return func(r *http.Request) Priority {
+		pattern := routePattern(r)
+		for _, prefix := range prefixes {
+			// the prefix must end on a segment boundary so /admin doesn't cover /administrators
+			prefix = strings.TrimSuffix(prefix, "/")
+			if pattern == prefix || strings.HasPrefix(pattern, prefix+"/") {
+				return PriorityBypass
+			}
+		}
+		return PriorityNormal
+	}
+}
+
+func (limiter *ConcurrencyLimiter) InFlight() int {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	return limiter.inFlight
+}
+
+func (limiter *ConcurrencyLimiter) Queued() int {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	return limiter.queues[PriorityNormal].Len() + limiter.queues[PriorityHigh].Len()
+}
+
+func (limiter *ConcurrencyLimiter) acquire(ctx context.Context, priority Priority) bool {
+	limiter.mu.Lock()
+	if limiter.inFlight < limiter.opts.MaxInFlight {
+		limiter.inFlight += 1
+		limiter.mu.Unlock()
+		return true
+	}
+	if limiter.queues[PriorityNormal].Len()+limiter.queues[PriorityHigh].Len() >= limiter.opts.MaxQueue {
+		limiter.mu.Unlock()
+		return false
+	}
+	ready := make(chan struct{})
+	queue := limiter.queues[priority]
+	elem := queue.PushBack(ready)
+	limiter.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if limiter.opts.MaxWait > 0 {
+		timer := time.NewTimer(limiter.opts.MaxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case <-ready:
+		return true
+	case <-timeout:
+	case <-ctx.Done():
+	}
+
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	select {
+	case <-ready:
+		// the slot was handed over while giving up so it is kept
+		return true
+	default:
+		queue.Remove(elem)
+		return false
+	}
+}
+
+func (limiter *ConcurrencyLimiter) release() {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	// a released slot is handed directly to the next waiter so it can't be taken by a new request
+	for _, priority := range []Priority{PriorityHigh, PriorityNormal} {
+		queue := limiter.queues[priority]
+		if front := queue.Front(); front != nil {
+			queue.Remove(front)
+			close(front.Value.(chan struct{}))
+			return
+		}
+	}
+	limiter.inFlight -= 1
+}
+
+func (limiter *ConcurrencyLimiter) Middleware() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			priority := PriorityNormal
+			if limiter.opts.Classify != nil {
+				priority = limiter.opts.Classify(r)
+			}
+			if priority != PriorityHigh && priority != PriorityBypass {
+				priority = PriorityNormal
+			}
+			if priority == PriorityBypass {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !limiter.acquire(r.Context(), priority) {
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(limiter.opts.RetryAfter)))
+				HandleError(w, r, ErrOverloaded)
+				return
+			}
+			defer limiter.release()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package httprouter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestConcurrencyLimiter(t *testing.T) {
+	limiter := NewConcurrencyLimiter(ConcurrencyOptions{
+		MaxInFlight: 1,
+		MaxQueue:    2,
+		MaxWait:     time.Second,
+		Classify: func(r *http.Request) Priority {
+			if r.Header.Get("X-Priority") == "high" {
+				return PriorityHigh
+			}
+			return BypassPrefixes("/admin")(r)
+		},
+	})
+
+	unblock := make(chan struct{})
+	var mu sync.Mutex
+	order := make([]string, 0)
+
+	r := NewRouter()
+	r.Use(limiter.Middleware())
+	r.Get("/work", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		order = append(order, r.Header.Get("X-Name"))
+		mu.Unlock()
+		<-unblock
+	})
+	admin := r.Prefix("/admin").SubRouter()
+	admin.Get("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	})
+
+	send := func(name string, priority string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/work", nil)
+		req.Header.Set("X-Name", name)
+		req.Header.Set("X-Priority", priority)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() { defer wg.Done(); send("first", "") }()
+	waitFor(t, func() bool { return limiter.InFlight() == 1 })
+	go func() { defer wg.Done(); send("normal", "") }()
+	waitFor(t, func() bool { return limiter.Queued() == 1 })
+	go func() { defer wg.Done(); send("high", "high") }()
+	waitFor(t, func() bool { return limiter.Queued() == 2 })
+
+	shed := send("shed", "")
+	if shed.Code != http.StatusServiceUnavailable || shed.Header().Get("Retry-After") != "1" {
+		t.Errorf("Expected a request beyond the queue to be shed with a 503 but got %d %v", shed.Code, shed.Header())
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/admin/health", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "OK" {
+		t.Errorf("Expected admin routes to bypass the limit but got %d %q", w.Code, w.Body.String())
+	}
+
+	close(unblock)
+	wg.Wait()
+
+	expected := []string{"first", "high", "normal"}
+	for i := range expected {
+		if i >= len(order) || order[i] != expected[i] {
+			t.Fatalf("Expected requests to run in order %v but got %v", expected, order)
+		}
+	}
+	if limiter.InFlight() != 0 || limiter.Queued() != 0 {
+		t.Errorf("Expected the gauges to return to 0 but got %d in flight and %d queued", limiter.InFlight(), limiter.Queued())
+	}
+}
+
+func TestConcurrencyLimiterMaxWait(t *testing.T) {
+	limiter := NewConcurrencyLimiter(ConcurrencyOptions{MaxInFlight: 1, MaxQueue: 1, MaxWait: 10 * time.Millisecond})
+
+	unblock := make(chan struct{})
+	handler := limiter.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-unblock
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+		close(done)
+	}()
+	waitFor(t, func() bool { return limiter.InFlight() == 1 })
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusServiceUnavailable || limiter.Queued() != 0 {
+		t.Errorf("Expected a queued request to be shed after the max wait but got %d with %d queued", w.Code, limiter.Queued())
+	}
+
+	close(unblock)
+	<-done
+}
+
+func TestConcurrencyLimiterUnknownPriority(t *testing.T) {
+	limiter := NewConcurrencyLimiter(ConcurrencyOptions{
+		MaxInFlight: 1,
+		Classify: func(r *http.Request) Priority {
+			p, _ := strconv.Atoi(r.Header.Get("X-Priority"))
+			return Priority(p)
+		},
+	})
+	handler := limiter.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	}))
+
+	for _, priority := range []string{"-1", "3", "5"} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Priority", priority)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected priority %s to be served as a normal request but got %d", priority, w.Code)
+		}
+	}
+}
+
+func TestConcurrencyLimiterInvalidOptions(t *testing.T) {
+	defer func() {
+		if rec := recover(); rec == nil {
+			t.Errorf("Expected a limiter without MaxInFlight to panic")
+		}
+	}()
+	NewConcurrencyLimiter(ConcurrencyOptions{})
+}
+
+func TestBypassPrefixes(t *testing.T) {
+	classify := BypassPrefixes("/admin", "/healthz/")
+
+	type Test struct {
+		pattern  string
+		priority Priority
+	}
+
+	testTable := []Test{
+		{pattern: "/admin", priority: PriorityBypass},
+		{pattern: "/admin/users", priority: PriorityBypass},
+		{pattern: "/administrators", priority: PriorityNormal},
+		{pattern: "/healthz", priority: PriorityBypass},
+		{pattern: "/healthzz", priority: PriorityNormal},
+		{pattern: "/orders", priority: PriorityNormal},
+	}
+
+	for i, test := range testTable {
+		req := httptest.NewRequest("GET", test.pattern, nil)
+		req = req.WithContext(context.WithValue(req.Context(), routeKey, &RouteMatch{Pattern: test.pattern}))
+		if priority := classify(req); priority != test.priority {
+			t.Errorf("Failed test %d, expected priority %d for %s but got %d", i, test.priority, test.pattern, priority)
+		}
+	}
+}
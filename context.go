@@ -3,12 +3,39 @@ package httprouter
 import (
 	"context"
 	"net/http"
+	"sync"
 )
 
 type varskey int
 
-// the id will always be unique since the varskey type only exists in this package
-var id = varskey(1)
+// the ids will always be unique since the varskey type only exists in this package
+var (
+	id              = varskey(1)
+	errorHandlerKey = varskey(2)
+	routeKey        = varskey(3)
+	requestIDKey    = varskey(4)
+	cspNonceKey     = varskey(5)
+	clientInfoKey   = varskey(6)
+	principalKey    = varskey(7)
+	claimsKey       = varskey(8)
+	csrfTokenKey    = varskey(9)
+	sessionKey      = varskey(10)
+	traceKey        = varskey(11)
+	requestStateKey = varskey(12)
+)
+
+// requestState is shared by the requests derived from the one the router received, so middlewares can make values
+// available to the middlewares that run before them such as the access log
+type requestState struct {
+	mu        sync.Mutex
+	requestID string
+	client    *clientInfo
+}
+
+func stateOf(r *http.Request) *requestState {
+	state, _ := r.Context().Value(requestStateKey).(*requestState)
+	return state
+}
 
 func setVar(r *http.Request, key string, value string) {
 	vars := Vars(r)
@@ -24,3 +51,38 @@ func Vars(r *http.Request) map[string]string {
 	}
 	return val.(map[string]string)
 }
+
+// RouteMatch describes the registered route that matched a request, it can identify requests in logs and metrics
+// without the cardinality of the path
+type RouteMatch struct {
+	Pattern string
+	Method  string
+
+	// Prefix is the combined prefix of the routers the route was registered on
+	Prefix string
+	Name   string
+
+	entry *routeEntry
+}
+
+// CurrentRoute is the route that matched the request, or nil if the router didn't match one
+func CurrentRoute(r *http.Request) *RouteMatch {
+	match, _ := r.Context().Value(routeKey).(*RouteMatch)
+	return match
+}
+
+func matchedRoute(r *http.Request) *routeEntry {
+	match := CurrentRoute(r)
+	if match == nil {
+		return nil
+	}
+	return match.entry
+}
+
+func routePattern(r *http.Request) string {
+	match := CurrentRoute(r)
+	if match == nil {
+		return ""
+	}
+	return match.Pattern
+}
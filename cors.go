@@ -0,0 +1,196 @@
+package httprouter
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type CorsOptions struct {
+	// AllowedOrigins may contain "*" to allow any origin or a single wildcard such as "https://*.example.com"
+	AllowedOrigins        []string
+	AllowedOriginPatterns []*regexp.Regexp
+	AllowOriginFunc       func(origin string, r *http.Request) bool
+
+	// AllowedMethods are allowed in addition to the CORS-safelisted GET, HEAD and POST
+	AllowedMethods []string
+
+	// AllowedHeaders may contain "*" to allow any header
+	AllowedHeaders []string
+
+	ExposedHeaders []string
+
+	// AllowCredentials can't be combined with a "*" origin since the Fetch spec forbids it
+	AllowCredentials bool
+
+	// MaxAge is how many seconds a preflight response may be cached, it is omitted when 0
+	MaxAge int
+
+	AllowPrivateNetwork bool
+}
+
+func DefaultCorsOptions() CorsOptions {
+	return CorsOptions{
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedHeaders: []string{"Content-Type", "Authorization"},
+	}
+}
+
+func CorsMiddleware() Middleware {
+	return CorsMiddlewareWithOptions(DefaultCorsOptions())
+}
+
+type corsPolicy struct {
+	CorsOptions
+	anyOrigin      bool
+	origins        map[string]bool
+	wildcards      [][2]string
+	methods        map[string]bool
+	anyHeader      bool
+	headers        map[string]bool
+	exposedHeaders string
+}
+
+func CorsMiddlewareWithOptions(opts CorsOptions) Middleware {
+	policy := corsPolicy{
+		CorsOptions:    opts,
+		origins:        make(map[string]bool),
+		methods:        map[string]bool{"GET": true, "HEAD": true, "POST": true},
+		headers:        make(map[string]bool),
+		exposedHeaders: strings.Join(opts.ExposedHeaders, ", "),
+	}
+	for _, origin := range opts.AllowedOrigins {
+		if origin == "*" {
+			policy.anyOrigin = true
+		} else if i := strings.IndexByte(origin, '*'); i >= 0 {
+			policy.wildcards = append(policy.wildcards, [2]string{strings.ToLower(origin[:i]), strings.ToLower(origin[i+1:])})
+		} else {
+			policy.origins[strings.ToLower(origin)] = true
+		}
+	}
+	for _, method := range opts.AllowedMethods {
+		policy.methods[method] = true
+	}
+	for _, header := range opts.AllowedHeaders {
+		if header == "*" {
+			policy.anyHeader = true
+		} else {
+			policy.headers[strings.ToLower(header)] = true
+		}
+	}
+	if policy.anyOrigin && opts.AllowCredentials {
+		panic("httprouter: cors credentials cannot be allowed for the \"*\" origin")
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == "OPTIONS" && r.Header.Get("Access-Control-Request-Method") != "" {
+				policy.preflight(w, r)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			policy.actual(w, r)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (policy *corsPolicy) allowOrigin(origin string, r *http.Request) bool {
+	if policy.anyOrigin {
+		return true
+	}
+	lower := strings.ToLower(origin)
+	if policy.origins[lower] {
+		return true
+	}
+	for _, wildcard := range policy.wildcards {
+		prefix, suffix := wildcard[0], wildcard[1]
+		if len(lower) > len(prefix)+len(suffix) && strings.HasPrefix(lower, prefix) && strings.HasSuffix(lower, suffix) {
+			return true
+		}
+	}
+	for _, pattern := range policy.AllowedOriginPatterns {
+		if pattern.MatchString(origin) {
+			return true
+		}
+	}
+	return policy.AllowOriginFunc != nil && policy.AllowOriginFunc(origin, r)
+}
+
+// writes the headers common to preflight and actual responses, returning false if the origin isn't allowed
+func (policy *corsPolicy) writeOrigin(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || !policy.allowOrigin(origin, r) {
+		return false
+	}
+
+	if policy.anyOrigin {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	} else {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+	}
+	if policy.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+	return true
+}
+
+func (policy *corsPolicy) actual(w http.ResponseWriter, r *http.Request) {
+	if !policy.anyOrigin {
+		w.Header().Add("Vary", "Origin")
+	}
+	if !policy.writeOrigin(w, r) {
+		return
+	}
+	if policy.exposedHeaders != "" {
+		w.Header().Set("Access-Control-Expose-Headers", policy.exposedHeaders)
+	}
+}
+
+func (policy *corsPolicy) preflight(w http.ResponseWriter, r *http.Request) {
+	header := w.Header()
+	if !policy.anyOrigin {
+		header.Add("Vary", "Origin")
+	}
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+	if policy.AllowPrivateNetwork {
+		header.Add("Vary", "Access-Control-Request-Private-Network")
+	}
+
+	method := r.Header.Get("Access-Control-Request-Method")
+	if !policy.methods[method] {
+		return
+	}
+
+	requested := make([]string, 0)
+	for _, value := range r.Header.Values("Access-Control-Request-Headers") {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+			if !policy.anyHeader && !policy.headers[name] {
+				return
+			}
+			requested = append(requested, name)
+		}
+	}
+
+	if !policy.writeOrigin(w, r) {
+		return
+	}
+	header.Set("Access-Control-Allow-Methods", method)
+	if len(requested) > 0 {
+		// the requested headers are echoed since "*" is treated as a literal header name for credentialed requests
+		header.Set("Access-Control-Allow-Headers", strings.Join(requested, ", "))
+	}
+	if policy.MaxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(policy.MaxAge))
+	}
+	if policy.AllowPrivateNetwork && r.Header.Get("Access-Control-Request-Private-Network") == "true" {
+		header.Set("Access-Control-Allow-Private-Network", "true")
+	}
+}
@@ -0,0 +1,239 @@
+package httprouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func strictActual(origin string) map[string]string {
+	return map[string]string{
+		"Access-Control-Allow-Origin":      origin,
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Expose-Headers":    "X-Total-Count, X-Request-ID",
+	}
+}
+
+func TestCorsMiddleware(t *testing.T) {
+	strict := CorsOptions{
+		AllowedOrigins:        []string{"https://app.example.com", "https://*.tenant.example.com"},
+		AllowedOriginPatterns: []*regexp.Regexp{regexp.MustCompile(`^http://localhost:\d+$`)},
+		AllowOriginFunc: func(origin string, r *http.Request) bool {
+			return origin == "https://partner.example.org"
+		},
+		AllowedMethods:      []string{"PUT", "DELETE"},
+		AllowedHeaders:      []string{"Content-Type", "X-Request-ID"},
+		ExposedHeaders:      []string{"X-Total-Count", "X-Request-ID"},
+		AllowCredentials:    true,
+		MaxAge:              600,
+		AllowPrivateNetwork: true,
+	}
+	public := CorsOptions{
+		AllowedOrigins: []string{"*"},
+		AllowedHeaders: []string{"*"},
+	}
+
+	type Test struct {
+		name     string
+		opts     CorsOptions
+		method   string
+		headers  map[string]string
+		status   int
+		expected map[string]string
+		vary     []string
+		reached  bool
+	}
+
+	testTable := []Test{
+		{name: "same origin request without an origin", opts: strict, method: "GET",
+			status: http.StatusOK, vary: []string{"Origin"}, reached: true},
+		{name: "actual request from an allowed origin", opts: strict, method: "GET",
+			headers: map[string]string{"Origin": "https://app.example.com"},
+			status:  http.StatusOK, reached: true, vary: []string{"Origin"},
+			expected: strictActual("https://app.example.com")},
+		{name: "actual request from a disallowed origin", opts: strict, method: "GET",
+			headers: map[string]string{"Origin": "https://evil.example.net"},
+			status:  http.StatusOK, reached: true, vary: []string{"Origin"}},
+		{name: "null origin is not allowed", opts: strict, method: "GET",
+			headers: map[string]string{"Origin": "null"},
+			status:  http.StatusOK, reached: true, vary: []string{"Origin"}},
+		{name: "wildcard subdomain origin", opts: strict, method: "GET",
+			headers: map[string]string{"Origin": "https://acme.tenant.example.com"},
+			status:  http.StatusOK, reached: true, vary: []string{"Origin"},
+			expected: strictActual("https://acme.tenant.example.com")},
+		{name: "wildcard does not match the bare domain", opts: strict, method: "GET",
+			headers: map[string]string{"Origin": "https://.tenant.example.com"},
+			status:  http.StatusOK, reached: true, vary: []string{"Origin"}},
+		{name: "regex origin", opts: strict, method: "GET",
+			headers: map[string]string{"Origin": "http://localhost:3000"},
+			status:  http.StatusOK, reached: true, vary: []string{"Origin"},
+			expected: strictActual("http://localhost:3000")},
+		{name: "func origin", opts: strict, method: "GET",
+			headers: map[string]string{"Origin": "https://partner.example.org"},
+			status:  http.StatusOK, reached: true, vary: []string{"Origin"},
+			expected: strictActual("https://partner.example.org")},
+		{name: "non preflight options request reaches the handler", opts: strict, method: "OPTIONS",
+			headers: map[string]string{"Origin": "https://app.example.com"},
+			status:  http.StatusOK, reached: true, vary: []string{"Origin"},
+			expected: strictActual("https://app.example.com")},
+		{name: "preflight request", opts: strict, method: "OPTIONS",
+			headers: map[string]string{
+				"Origin":                         "https://app.example.com",
+				"Access-Control-Request-Method":  "PUT",
+				"Access-Control-Request-Headers": "content-type,x-request-id",
+			},
+			status: http.StatusNoContent,
+			vary:   []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers", "Access-Control-Request-Private-Network"},
+			expected: map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.example.com",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Allow-Methods":     "PUT",
+				"Access-Control-Allow-Headers":     "content-type, x-request-id",
+				"Access-Control-Max-Age":           "600",
+			}},
+		{name: "preflight for a disallowed method", opts: strict, method: "OPTIONS",
+			headers: map[string]string{"Origin": "https://app.example.com", "Access-Control-Request-Method": "PATCH"},
+			status:  http.StatusNoContent,
+			vary:    []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers", "Access-Control-Request-Private-Network"}},
+		{name: "preflight for a disallowed header", opts: strict, method: "OPTIONS",
+			headers: map[string]string{
+				"Origin":                         "https://app.example.com",
+				"Access-Control-Request-Method":  "PUT",
+				"Access-Control-Request-Headers": "x-secret",
+			},
+			status: http.StatusNoContent,
+			vary:   []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers", "Access-Control-Request-Private-Network"}},
+		{name: "preflight for a disallowed origin", opts: strict, method: "OPTIONS",
+			headers: map[string]string{"Origin": "https://evil.example.net", "Access-Control-Request-Method": "GET"},
+			status:  http.StatusNoContent,
+			vary:    []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers", "Access-Control-Request-Private-Network"}},
+		{name: "private network preflight", opts: strict, method: "OPTIONS",
+			headers: map[string]string{
+				"Origin":                                 "https://app.example.com",
+				"Access-Control-Request-Method":          "GET",
+				"Access-Control-Request-Private-Network": "true",
+			},
+			status: http.StatusNoContent,
+			vary:   []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers", "Access-Control-Request-Private-Network"},
+			expected: map[string]string{
+				"Access-Control-Allow-Origin":          "https://app.example.com",
+				"Access-Control-Allow-Credentials":     "true",
+				"Access-Control-Allow-Methods":         "GET",
+				"Access-Control-Max-Age":               "600",
+				"Access-Control-Allow-Private-Network": "true",
+			}},
+		{name: "public actual request", opts: public, method: "POST",
+			headers: map[string]string{"Origin": "https://anyone.example.net"},
+			status:  http.StatusOK, reached: true,
+			expected: map[string]string{"Access-Control-Allow-Origin": "*"}},
+		{name: "public preflight with any header", opts: public, method: "OPTIONS",
+			headers: map[string]string{
+				"Origin":                         "https://anyone.example.net",
+				"Access-Control-Request-Method":  "POST",
+				"Access-Control-Request-Headers": "x-anything",
+			},
+			status: http.StatusNoContent,
+			vary:   []string{"Access-Control-Request-Method", "Access-Control-Request-Headers"},
+			expected: map[string]string{
+				"Access-Control-Allow-Origin":  "*",
+				"Access-Control-Allow-Methods": "POST",
+				"Access-Control-Allow-Headers": "x-anything",
+			}},
+	}
+
+	corsHeaders := []string{
+		"Access-Control-Allow-Origin",
+		"Access-Control-Allow-Credentials",
+		"Access-Control-Allow-Methods",
+		"Access-Control-Allow-Headers",
+		"Access-Control-Expose-Headers",
+		"Access-Control-Max-Age",
+		"Access-Control-Allow-Private-Network",
+	}
+
+	for _, test := range testTable {
+		reached := false
+		handler := CorsMiddlewareWithOptions(test.opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reached = true
+		}))
+
+		req := httptest.NewRequest(test.method, "/products", nil)
+		for key, value := range test.headers {
+			req.Header.Set(key, value)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if w.Code != test.status || reached != test.reached {
+			t.Errorf("%s: expected status %d and reached %v but got %d and %v", test.name, test.status, test.reached, w.Code, reached)
+		}
+		for _, key := range corsHeaders {
+			if w.Header().Get(key) != test.expected[key] {
+				t.Errorf("%s: expected %s to be %q but got %q", test.name, key, test.expected[key], w.Header().Get(key))
+			}
+		}
+		if vary := w.Header().Values("Vary"); !reflect.DeepEqual(vary, test.vary) && (len(vary) != 0 || len(test.vary) != 0) {
+			t.Errorf("%s: expected Vary %v but got %v", test.name, test.vary, vary)
+		}
+	}
+}
+
+func TestCorsCredentialsWithAnyOrigin(t *testing.T) {
+	defer func() {
+		if rec := recover(); rec == nil || !strings.Contains(rec.(string), "credentials") {
+			t.Errorf("Expected allowing credentials for any origin to panic but got %v", rec)
+		}
+	}()
+	CorsMiddlewareWithOptions(CorsOptions{AllowedOrigins: []string{"*"}, AllowCredentials: true})
+}
+
+func TestCorsRouterPreflight(t *testing.T) {
+	cors := func(methods ...string) Middleware {
+		return CorsMiddlewareWithOptions(CorsOptions{AllowedOrigins: []string{"https://app.example.com"}, AllowedMethods: methods})
+	}
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Updated"))
+	}
+
+	r := NewRouter()
+	r.Use(cors("PUT"))
+	r.Put("/products", ok)
+
+	// middlewares added to sub routers or with With only see requests for their own routes
+	r2 := NewRouter()
+	api := r2.Prefix("/api").SubRouter()
+	api.Use(cors("PATCH"))
+	api.Route("PATCH", "/products", ok)
+
+	r2.Get("/orders", ok)
+	r2.With(cors("DELETE")).Delete("/orders", ok)
+
+	type Test struct {
+		router  *ServerRouter
+		url     string
+		method  string
+		methods string
+	}
+
+	testTable := []Test{
+		{router: r, url: "/products", method: "PUT", methods: "PUT"},
+		{router: r2, url: "/api/products", method: "PATCH", methods: "PATCH"},
+		{router: r2, url: "/orders", method: "DELETE", methods: "DELETE"},
+		{router: r2, url: "/orders", method: "GET", methods: ""},
+	}
+
+	for i, test := range testTable {
+		req := httptest.NewRequest("OPTIONS", test.url, nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		req.Header.Set("Access-Control-Request-Method", test.method)
+		w := httptest.NewRecorder()
+		test.router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusNoContent || w.Header().Get("Access-Control-Allow-Methods") != test.methods {
+			t.Errorf("Failed test %d, expected the router to answer the preflight request through the middleware but got %d %v", i, w.Code, w.Header())
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package httprouter
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const csrfExemptMarker = "csrf.exempt"
+
+// CSRFExempt opts a route out of the protection of a CSRFMiddleware added to its router, such as r.With(CSRFExempt()).Post(...)
+func CSRFExempt() Middleware {
+	return func(next http.Handler) http.Handler {
+		return &routeMarker{Handler: next, key: csrfExemptMarker, value: true}
+	}
+}
+
+type CSRFOptions struct {
+	// Secret signs the tokens so only tokens issued by the middleware are accepted, it is required. Tokens aren't bound
+	// to a session, so a sibling subdomain that can set cookies could still plant a token it was issued
+	Secret []byte
+
+	// CookieName defaults to csrf_token, HeaderName to X-CSRF-Token and FormField to csrf_token
+	CookieName string
+	HeaderName string
+	FormField  string
+
+	CookiePath   string
+	CookieDomain string
+	MaxAge       int
+	SameSite     http.SameSite
+
+	// TrustedOrigins are other origins such as https://app.example.com allowed to make unsafe requests
+	TrustedOrigins []string
+}
+
+type csrfPolicy struct {
+	opts    CSRFOptions
+	origins map[string]bool
+}
+
+func (policy *csrfPolicy) sign(nonce string) string {
+	mac := hmac.New(sha256.New, policy.opts.Secret)
+	mac.Write([]byte(nonce))
+	return nonce + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (policy *csrfPolicy) newToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return policy.sign(base64.RawURLEncoding.EncodeToString(b))
+}
+
+func (policy *csrfPolicy) validToken(token string) bool {
+	nonce, _, ok := strings.Cut(token, ".")
+	return ok && nonce != "" && hmac.Equal([]byte(policy.sign(nonce)), []byte(token))
+}
+
+// checks the Origin header, or the Referer of TLS requests without one, against the request's own origin
+func (policy *csrfPolicy) sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		if RequestScheme(r) != "https" {
+			return true
+		}
+		// browsers always send a Referer to the same origin over TLS unless the page opts out
+		referer, err := url.Parse(r.Header.Get("Referer"))
+		if err != nil || referer.Host == "" {
+			return false
+		}
+		origin = referer.Scheme + "://" + referer.Host
+	}
+	self := RequestScheme(r) + "://" + RequestHost(r)
+	return strings.EqualFold(origin, self) || policy.origins[strings.ToLower(origin)]
+}
+
+func (policy *csrfPolicy) submittedToken(r *http.Request) string {
+	if token := r.Header.Get(policy.opts.HeaderName); token != "" {
+		return token
+	}
+	return r.PostFormValue(policy.opts.FormField)
+}
+
+func isSafeMethod(method string) bool {
+	return method == "GET" || method == "HEAD" || method == "OPTIONS" || method == "TRACE"
+}
+
+func csrfExempt(r *http.Request) bool {
+	rt := matchedRoute(r)
+	return rt != nil && len(rt.meta.markers[csrfExemptMarker]) > 0
+}
+
+// CSRFMiddleware protects against cross site request forgery with signed double submit cookies. Unsafe requests must come
+// from a trusted origin and send the cookie's token in a header or form field, otherwise they get a 403
+func CSRFMiddleware(opts CSRFOptions) Middleware {
+	if len(opts.Secret) == 0 {
+		panic("httprouter: csrf middleware requires a secret")
+	}
+	if opts.CookieName == "" {
+		opts.CookieName = "csrf_token"
+	}
+	if opts.HeaderName == "" {
+		opts.HeaderName = "X-CSRF-Token"
+	}
+	if opts.FormField == "" {
+		opts.FormField = "csrf_token"
+	}
+	if opts.CookiePath == "" {
+		opts.CookiePath = "/"
+	}
+	if opts.SameSite == 0 {
+		opts.SameSite = http.SameSiteLaxMode
+	}
+
+	policy := &csrfPolicy{opts: opts, origins: make(map[string]bool)}
+	for _, origin := range opts.TrustedOrigins {
+		policy.origins[strings.ToLower(strings.TrimSuffix(origin, "/"))] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if csrfExempt(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			token := ""
+			if cookie, err := r.Cookie(opts.CookieName); err == nil && policy.validToken(cookie.Value) {
+				token = cookie.Value
+			}
+
+			if !isSafeMethod(r.Method) {
+				if token == "" || !policy.sameOrigin(r) || !hmac.Equal([]byte(policy.submittedToken(r)), []byte(token)) {
+					HandleError(w, r, ErrForbidden)
+					return
+				}
+			}
+
+			if token == "" {
+				token = policy.newToken()
+				http.SetCookie(w, &http.Cookie{
+					Name:     opts.CookieName,
+					Value:    token,
+					Path:     opts.CookiePath,
+					Domain:   opts.CookieDomain,
+					MaxAge:   opts.MaxAge,
+					Secure:   RequestScheme(r) == "https",
+					SameSite: opts.SameSite,
+				})
+			}
+			w.Header().Add("Vary", "Cookie")
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), csrfTokenKey, token)))
+		})
+	}
+}
+
+// CSRFToken is the token to submit with forms in the field or header configured for CSRFMiddleware
+func CSRFToken(r *http.Request) string {
+	token, _ := r.Context().Value(csrfTokenKey).(string)
+	return token
+}
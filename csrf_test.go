@@ -0,0 +1,113 @@
+package httprouter
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCSRFMiddleware(t *testing.T) {
+	r := NewRouter()
+	r.Use(CSRFMiddleware(CSRFOptions{
+		Secret:         []byte("csrf-secret"),
+		TrustedOrigins: []string{"https://app.example.com/"},
+	}))
+	r.Get("/form", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(CSRFToken(r)))
+	})
+	r.Post("/form", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("submitted"))
+	})
+	r.With(CSRFExempt()).Post("/webhook", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("received"))
+	})
+	hooks := r.Prefix("/hooks").SubRouter()
+	hooks.Use(CSRFExempt())
+	hooks.Post("/payments", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("received"))
+	})
+
+	req := httptest.NewRequest("GET", "/form", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "csrf_token" || cookies[0].Value != w.Body.String() {
+		t.Fatalf("Expected a csrf_token cookie matching the token %q but got %v", w.Body.String(), cookies)
+	}
+	token := cookies[0].Value
+
+	// a token that is reused by the cookie isn't issued again
+	req = httptest.NewRequest("GET", "/form", nil)
+	req.AddCookie(&http.Cookie{Name: "csrf_token", Value: token})
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Header().Get("Set-Cookie") != "" || w.Body.String() != token {
+		t.Errorf("Expected the existing token to be reused but got %q with Set-Cookie %q", w.Body.String(), w.Header().Get("Set-Cookie"))
+	}
+
+	type Test struct {
+		url     string
+		cookie  string
+		header  string
+		form    string
+		origin  string
+		referer string
+		tls     bool
+		status  int
+	}
+
+	forged := "forged.c2lnbmF0dXJl"
+
+	testTable := []Test{
+		{url: "/form", cookie: token, header: token, status: http.StatusOK},
+		{url: "/form", cookie: token, form: token, status: http.StatusOK},
+		{url: "/form", cookie: token, status: http.StatusForbidden},
+		{url: "/form", header: token, status: http.StatusForbidden},
+		{url: "/form", cookie: token, header: token + "x", status: http.StatusForbidden},
+		{url: "/form", cookie: forged, header: forged, status: http.StatusForbidden},
+		{url: "/form", cookie: token, header: token, origin: "http://example.com", status: http.StatusOK},
+		{url: "/form", cookie: token, header: token, origin: "https://app.example.com", status: http.StatusOK},
+		{url: "/form", cookie: token, header: token, origin: "https://evil.example", status: http.StatusForbidden},
+		{url: "/form", cookie: token, header: token, origin: "null", status: http.StatusForbidden},
+		{url: "/form", cookie: token, header: token, tls: true, status: http.StatusForbidden},
+		{url: "/form", cookie: token, header: token, tls: true, referer: "https://example.com/form", status: http.StatusOK},
+		{url: "/form", cookie: token, header: token, tls: true, referer: "https://evil.example/form", status: http.StatusForbidden},
+		{url: "/webhook", status: http.StatusOK},
+		{url: "/hooks/payments", status: http.StatusOK},
+	}
+
+	for i, test := range testTable {
+		var req *http.Request
+		if test.form != "" {
+			req = httptest.NewRequest("POST", test.url, strings.NewReader(url.Values{"csrf_token": {test.form}}.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		} else {
+			req = httptest.NewRequest("POST", test.url, nil)
+		}
+		if test.cookie != "" {
+			req.AddCookie(&http.Cookie{Name: "csrf_token", Value: test.cookie})
+		}
+		if test.header != "" {
+			req.Header.Set("X-CSRF-Token", test.header)
+		}
+		if test.origin != "" {
+			req.Header.Set("Origin", test.origin)
+		}
+		if test.referer != "" {
+			req.Header.Set("Referer", test.referer)
+		}
+		if test.tls {
+			req.TLS = &tls.ConnectionState{}
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != test.status {
+			t.Errorf("Failed test %d, expected %d but got %d %q", i, test.status, w.Code, w.Body.String())
+		}
+	}
+}
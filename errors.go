@@ -0,0 +1,191 @@
+package httprouter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrRouteNotFound    = errors.New("httprouter: route not found")
+	ErrMethodNotAllowed = errors.New("httprouter: method not allowed")
+	ErrBadPattern       = errors.New("httprouter: bad route pattern")
+	ErrInternal         = errors.New("httprouter: internal router error")
+	ErrTimeout          = errors.New("httprouter: handler timed out")
+	ErrRateLimited      = errors.New("httprouter: rate limit exceeded")
+	ErrOverloaded       = errors.New("httprouter: too many requests in flight")
+	ErrForbidden        = errors.New("httprouter: forbidden")
+	ErrUnauthorized     = errors.New("httprouter: unauthorized")
+)
+
+type ErrorHandlerFunc = func(w http.ResponseWriter, r *http.Request, err error)
+
+type ErrorRenderer = func(w http.ResponseWriter, r *http.Request, err *HTTPError)
+
+type HandlerFuncE = func(w http.ResponseWriter, r *http.Request) error
+
+// PatternError is returned when a pattern in a registered route cannot be compiled
+type PatternError struct {
+	Pattern string
+	Err     error
+}
+
+func (e *PatternError) Error() string {
+	return fmt.Sprintf("httprouter: bad route pattern %q: %v", e.Pattern, e.Err)
+}
+
+func (e *PatternError) Unwrap() error {
+	return e.Err
+}
+
+func (e *PatternError) Is(target error) bool {
+	return target == ErrBadPattern
+}
+
+// HTTPError is an error that carries the response that should be rendered for it. The message and details are
+// sent to the client, the wrapped error is not
+type HTTPError struct {
+	Status  int
+	Code    string
+	Message string
+	Details any
+	Err     error
+}
+
+func NewHTTPError(status int, code string, message string) *HTTPError {
+	return &HTTPError{Status: status, Code: code, Message: message}
+}
+
+func (e *HTTPError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.Status)
+	}
+	if e.Err != nil {
+		return fmt.Sprintf("%d %s: %v", e.Status, msg, e.Err)
+	}
+	return fmt.Sprintf("%d %s", e.Status, msg)
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+func errorStatus(err error) int {
+	var httpErr *HTTPError
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &httpErr):
+		return httpErr.Status
+	case errors.As(err, &maxBytesErr):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrRouteNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrMethodNotAllowed):
+		return http.StatusMethodNotAllowed
+	case errors.Is(err, ErrTimeout), errors.Is(err, ErrOverloaded):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, ErrRateLimited):
+		return http.StatusTooManyRequests
+	case errors.Is(err, ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, ErrUnauthorized):
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// converts any error into an HTTPError without exposing the message of errors that aren't already HTTPErrors
+func toHTTPError(err error) *HTTPError {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr
+	}
+	return &HTTPError{Status: errorStatus(err), Err: err}
+}
+
+// HandleError passes the error to the error handling of the router serving the request, so middlewares and handlers
+// render errors the same way the router does
+func HandleError(w http.ResponseWriter, r *http.Request, err error) {
+	handler, ok := r.Context().Value(errorHandlerKey).(ErrorHandlerFunc)
+	if !ok {
+		TextErrorRenderer(w, r, toHTTPError(err))
+		return
+	}
+	handler(w, r, err)
+}
+
+// HandleE adapts a handler returning an error into a http.HandlerFunc that passes the error to HandleError
+func HandleE(handler HandlerFuncE) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := handler(w, r); err != nil {
+			HandleError(w, r, err)
+		}
+	}
+}
+
+func errorMessage(err *HTTPError) string {
+	if err.Message != "" {
+		return err.Message
+	}
+	return strings.ToLower(http.StatusText(err.Status))
+}
+
+func TextErrorRenderer(w http.ResponseWriter, r *http.Request, err *HTTPError) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(err.Status)
+	w.Write([]byte(fmt.Sprintf("%d %s", err.Status, errorMessage(err))))
+}
+
+func JSONErrorRenderer(w http.ResponseWriter, r *http.Request, err *HTTPError) {
+	body := struct {
+		Status  int    `json:"status"`
+		Code    string `json:"code,omitempty"`
+		Message string `json:"message"`
+		Details any    `json:"details,omitempty"`
+	}{
+		Status:  err.Status,
+		Code:    err.Code,
+		Message: errorMessage(err),
+		Details: err.Details,
+	}
+	writeJSONError(w, "application/json", err.Status, body)
+}
+
+// ProblemErrorRenderer renders errors as RFC 9457 problem details
+func ProblemErrorRenderer(w http.ResponseWriter, r *http.Request, err *HTTPError) {
+	body := struct {
+		Type     string `json:"type"`
+		Title    string `json:"title"`
+		Status   int    `json:"status"`
+		Detail   string `json:"detail,omitempty"`
+		Instance string `json:"instance,omitempty"`
+		Code     string `json:"code,omitempty"`
+		Details  any    `json:"details,omitempty"`
+	}{
+		Type:     "about:blank",
+		Title:    http.StatusText(err.Status),
+		Status:   err.Status,
+		Detail:   err.Message,
+		Instance: r.URL.Path,
+		Code:     err.Code,
+		Details:  err.Details,
+	}
+	writeJSONError(w, "application/problem+json", err.Status, body)
+}
+
+func writeJSONError(w http.ResponseWriter, contentType string, status int, body any) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		TextErrorRenderer(w, nil, &HTTPError{Status: http.StatusInternalServerError})
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	w.Write(b)
+}
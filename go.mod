@@ -1,3 +1,3 @@
 module HttpRouter
 
-go 1.20
+go 1.21
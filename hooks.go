@@ -0,0 +1,67 @@
+package httprouter
+
+import (
+	"net/http"
+	"time"
+)
+
+// CompletedRequest summarizes a response once the router has served it, Route is nil if no route matched
+type CompletedRequest struct {
+	Route        *RouteMatch
+	Status       int
+	BytesWritten int64
+	Duration     time.Duration
+}
+
+type routerHooks struct {
+	onMatch    []func(r *http.Request, route *RouteMatch)
+	onNotFound []func(r *http.Request, status int)
+	onError    []func(r *http.Request, err error)
+	onComplete []func(r *http.Request, completed CompletedRequest)
+}
+
+// OnMatch is called when a request matches a route, before any of the route's middlewares. Hooks should be
+// added before the router starts serving requests, and unlike middlewares they apply to routes registered earlier
+func (router *ServerRouter) OnMatch(hook func(r *http.Request, route *RouteMatch)) {
+	router.hooks.onMatch = append(router.hooks.onMatch, hook)
+}
+
+// OnNotFound is called when no route matches a request with the status it will get, either 404 or 405
+func (router *ServerRouter) OnNotFound(hook func(r *http.Request, status int)) {
+	router.hooks.onNotFound = append(router.hooks.onNotFound, hook)
+}
+
+// OnError is called for errors handled by the router, including errors handlers pass to HandleError. Requests that
+// no route matches are only reported to OnNotFound
+func (router *ServerRouter) OnError(hook func(r *http.Request, err error)) {
+	router.hooks.onError = append(router.hooks.onError, hook)
+}
+
+// OnComplete is called after the response has been served
+func (router *ServerRouter) OnComplete(hook func(r *http.Request, completed CompletedRequest)) {
+	router.hooks.onComplete = append(router.hooks.onComplete, hook)
+}
+
+func (hooks *routerHooks) match(r *http.Request, route *RouteMatch) {
+	for _, hook := range hooks.onMatch {
+		hook(r, route)
+	}
+}
+
+func (hooks *routerHooks) notFound(r *http.Request, status int) {
+	for _, hook := range hooks.onNotFound {
+		hook(r, status)
+	}
+}
+
+func (hooks *routerHooks) error(r *http.Request, err error) {
+	for _, hook := range hooks.onError {
+		hook(r, err)
+	}
+}
+
+func (hooks *routerHooks) complete(r *http.Request, completed CompletedRequest) {
+	for _, hook := range hooks.onComplete {
+		hook(r, completed)
+	}
+}
@@ -0,0 +1,67 @@
+package httprouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestRouterHooks(t *testing.T) {
+	r := NewRouter()
+	r.Get("/users", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("users"))
+	})
+	r.RouteE("GET", "/fail", func(w http.ResponseWriter, r *http.Request) error {
+		return ErrForbidden
+	})
+
+	// hooks added after the routes still apply to them
+	var events []string
+	r.OnMatch(func(r *http.Request, route *RouteMatch) {
+		if CurrentRoute(r) != route {
+			t.Errorf("Expected the matched route on the request context")
+		}
+		events = append(events, "match "+route.Pattern)
+	})
+	r.OnNotFound(func(r *http.Request, status int) {
+		events = append(events, "not found "+strconv.Itoa(status))
+	})
+	r.OnError(func(r *http.Request, err error) {
+		events = append(events, "error "+err.Error())
+	})
+	r.OnComplete(func(r *http.Request, completed CompletedRequest) {
+		pattern := ""
+		if completed.Route != nil {
+			pattern = completed.Route.Pattern
+		}
+		if completed.Duration <= 0 {
+			t.Errorf("Expected the duration of the request to be recorded")
+		}
+		events = append(events, "complete "+pattern+" "+strconv.Itoa(completed.Status)+" "+strconv.FormatInt(completed.BytesWritten, 10))
+	})
+
+	type Test struct {
+		method string
+		url    string
+		events []string
+	}
+
+	testTable := []Test{
+		{method: "GET", url: "/users?page=1", events: []string{"match /users", "complete /users 200 5"}},
+		{method: "GET", url: "/fail", events: []string{"match /fail", "error httprouter: forbidden", "complete /fail 403 13"}},
+		{method: "GET", url: "/missing", events: []string{"not found 404", "complete  404 13"}},
+		{method: "POST", url: "/users", events: []string{"not found 405", "complete  405 22"}},
+		{method: "OPTIONS", url: "/users", events: []string{"complete  204 0"}},
+	}
+
+	for i, test := range testTable {
+		events = nil
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(test.method, test.url, nil))
+
+		if !reflect.DeepEqual(events, test.events) {
+			t.Errorf("Failed test %d, expected events %q but got %q", i, test.events, events)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package httprouter
+
+import (
+	"net/http"
+	"net/netip"
+	"sync/atomic"
+)
+
+type ipLists struct {
+	allow []netip.Prefix
+	deny  []netip.Prefix
+}
+
+// IPFilter matches client addresses against CIDR allow and deny lists. Denied addresses are always rejected, and when
+// the allow list isn't empty only addresses in it are accepted
+type IPFilter struct {
+	lists atomic.Pointer[ipLists]
+}
+
+func NewIPFilter(allow []string, deny []string) (*IPFilter, error) {
+	filter := &IPFilter{}
+	if err := filter.Reload(allow, deny); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+// Reload replaces both lists, requests being filtered concurrently see either the old or the new lists
+func (filter *IPFilter) Reload(allow []string, deny []string) error {
+	allowPrefixes, err := parsePrefixes(allow)
+	if err != nil {
+		return err
+	}
+	denyPrefixes, err := parsePrefixes(deny)
+	if err != nil {
+		return err
+	}
+	filter.lists.Store(&ipLists{allow: allowPrefixes, deny: denyPrefixes})
+	return nil
+}
+
+func (filter *IPFilter) Allowed(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	lists := filter.lists.Load()
+	if containsAddr(lists.deny, addr) {
+		return false
+	}
+	return len(lists.allow) == 0 || containsAddr(lists.allow, addr)
+}
+
+// Middleware rejects requests whose ClientIP isn't allowed. It can be added with Use on a SubRouter or With on a route
+func (filter *IPFilter) Middleware() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !filter.Allowed(ClientIP(r)) {
+				HandleError(w, r, ErrForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package httprouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestIPFilter(t *testing.T) {
+	filter, err := NewIPFilter([]string{"10.0.0.0/8", "2001:db8::/32"}, []string{"10.0.9.0/24", "10.0.0.5"})
+	if err != nil {
+		t.Fatalf("Failed to create filter: %v", err)
+	}
+
+	type Test struct {
+		ip      string
+		allowed bool
+	}
+
+	testTable := []Test{
+		{ip: "10.1.2.3", allowed: true},
+		{ip: "10.0.9.4", allowed: false},
+		{ip: "10.0.0.5", allowed: false},
+		{ip: "10.0.0.6", allowed: true},
+		{ip: "::ffff:10.1.2.3", allowed: true},
+		{ip: "2001:db8::1", allowed: true},
+		{ip: "2001:db9::1", allowed: false},
+		{ip: "192.168.1.1", allowed: false},
+		{ip: "not an ip", allowed: false},
+	}
+
+	for _, test := range testTable {
+		if filter.Allowed(test.ip) != test.allowed {
+			t.Errorf("Expected %s to be allowed %v", test.ip, test.allowed)
+		}
+	}
+
+	if _, err := NewIPFilter([]string{"10.0.0.0/33"}, nil); err == nil {
+		t.Errorf("Expected an invalid CIDR to return an error")
+	}
+	if err := filter.Reload([]string{"bad"}, nil); err == nil || !filter.Allowed("10.1.2.3") {
+		t.Errorf("Expected a failed reload to keep the previous lists")
+	}
+}
+
+func TestIPFilterMiddleware(t *testing.T) {
+	filter, _ := NewIPFilter([]string{"10.0.0.0/8"}, nil)
+
+	r := NewRouter()
+	r.Use(RealIPMiddleware("172.16.0.0/12"))
+	r.Get("/public", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Public"))
+	})
+	admin := r.Prefix("/admin").SubRouter()
+	admin.Use(filter.Middleware())
+	admin.Get("/stats", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Stats"))
+	})
+
+	send := func(url string, forwardedFor string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", url, nil)
+		req.RemoteAddr = "172.16.0.1:1234"
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := send("/admin/stats", "10.2.3.4"); w.Code != http.StatusOK || w.Body.String() != "Stats" {
+		t.Errorf("Expected an internal client to reach the admin route but got %d %q", w.Code, w.Body.String())
+	}
+	if w := send("/admin/stats", "198.51.100.1"); w.Code != http.StatusForbidden || w.Body.String() != "403 forbidden" {
+		t.Errorf("Expected an external client to be forbidden but got %d %q", w.Code, w.Body.String())
+	}
+	if w := send("/public", "198.51.100.1"); w.Code != http.StatusOK {
+		t.Errorf("Expected an external client to reach a public route but got %d", w.Code)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			send("/admin/stats", "198.51.100.1")
+		}()
+	}
+	filter.Reload([]string{"10.0.0.0/8", "198.51.100.0/24"}, nil)
+	wg.Wait()
+
+	if w := send("/admin/stats", "198.51.100.1"); w.Code != http.StatusOK {
+		t.Errorf("Expected the reloaded list to allow the client but got %d", w.Code)
+	}
+}
@@ -0,0 +1,357 @@
+package httprouter
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// JWTKey verifies tokens signed with Algorithm, Key is a []byte for HS256, an *rsa.PublicKey for RS256 or an *ecdsa.PublicKey for ES256
+type JWTKey struct {
+	ID        string
+	Algorithm string
+	Key       any
+}
+
+type JWTOptions struct {
+	Keys []JWTKey
+
+	// Issuer and Audience are only checked when they are set
+	Issuer   string
+	Audience string
+
+	// Leeway is the allowed clock skew when checking exp and nbf
+	Leeway time.Duration
+	Now    func() time.Time
+}
+
+// Claims are the verified claims of a bearer token, Raw holds every claim including the registered ones
+type Claims struct {
+	Subject   string
+	Issuer    string
+	Audience  []string
+	ExpiresAt time.Time
+	NotBefore time.Time
+	IssuedAt  time.Time
+	Scopes    []string
+	Raw       map[string]any
+}
+
+// JWTClaims is the claims verified by the JWT middleware, or nil if there are none
+func JWTClaims(r *http.Request) *Claims {
+	claims, _ := r.Context().Value(claimsKey).(*Claims)
+	return claims
+}
+
+func (claims *Claims) HasScope(scope string) bool {
+	return contains(claims.Scopes, scope)
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	K   string `json:"k"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func decodeSegment(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+func (k *jwk) toKey() (JWTKey, error) {
+	key := JWTKey{ID: k.Kid, Algorithm: k.Alg}
+	switch k.Kty {
+	case "oct":
+		secret, err := decodeSegment(k.K)
+		if err != nil || len(secret) == 0 {
+			return key, fmt.Errorf("invalid oct key %q", k.Kid)
+		}
+		key.Key = secret
+		if key.Algorithm == "" {
+			key.Algorithm = "HS256"
+		}
+	case "RSA":
+		n, err1 := decodeSegment(k.N)
+		e, err2 := decodeSegment(k.E)
+		if err1 != nil || err2 != nil || len(n) == 0 || len(e) == 0 || len(e) > 4 {
+			return key, fmt.Errorf("invalid RSA key %q", k.Kid)
+		}
+		key.Key = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		if key.Algorithm == "" {
+			key.Algorithm = "RS256"
+		}
+	case "EC":
+		x, err1 := decodeSegment(k.X)
+		y, err2 := decodeSegment(k.Y)
+		if err1 != nil || err2 != nil || len(x) != 32 || len(y) != 32 {
+			return key, fmt.Errorf("invalid EC key %q", k.Kid)
+		}
+		// ecdh rejects points that aren't on the curve
+		if _, err := ecdh.P256().NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return key, fmt.Errorf("invalid EC key %q: %w", k.Kid, err)
+		}
+		key.Key = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if key.Algorithm == "" {
+			key.Algorithm = "ES256"
+		}
+	default:
+		return key, fmt.Errorf("unsupported key type %q for key %q", k.Kty, k.Kid)
+	}
+	return key, nil
+}
+
+// ParseJWKS parses a JSON Web Key Set, keys for encryption or unsupported algorithms are skipped
+func ParseJWKS(data []byte) ([]JWTKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+	var keys []JWTKey
+	for _, k := range set.Keys {
+		// keys this package can't verify with are skipped so a shared key set can contain them
+		if k.Use == "enc" || !(k.Kty == "oct" || k.Kty == "RSA" || (k.Kty == "EC" && k.Crv == "P-256")) {
+			continue
+		}
+		key, err := k.toKey()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// LoadJWKS reads a JSON Web Key Set from a local file
+func LoadJWKS(path string) ([]JWTKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseJWKS(data)
+}
+
+func verifySignature(key JWTKey, signed []byte, signature []byte) bool {
+	digest := sha256.Sum256(signed)
+	switch k := key.Key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write(signed)
+		return key.Algorithm == "HS256" && hmac.Equal(mac.Sum(nil), signature)
+	case *rsa.PublicKey:
+		return key.Algorithm == "RS256" && rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], signature) == nil
+	case *ecdsa.PublicKey:
+		// ES256 signatures are the 32 byte r and s values concatenated rather than ASN.1
+		if key.Algorithm != "ES256" || len(signature) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(k, digest[:], r, s)
+	default:
+		return false
+	}
+}
+
+func numericDate(v any) time.Time {
+	f, ok := v.(float64)
+	if !ok {
+		return time.Time{}
+	}
+	return time.Unix(int64(f), 0)
+}
+
+func stringList(v any) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		var list []string
+		for _, s := range v {
+			if s, ok := s.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	default:
+		return nil
+	}
+}
+
+func parseClaims(raw map[string]any) *Claims {
+	claims := &Claims{Raw: raw}
+	claims.Subject, _ = raw["sub"].(string)
+	claims.Issuer, _ = raw["iss"].(string)
+	claims.Audience = stringList(raw["aud"])
+	claims.ExpiresAt = numericDate(raw["exp"])
+	claims.NotBefore = numericDate(raw["nbf"])
+	claims.IssuedAt = numericDate(raw["iat"])
+	// scope is a space separated string, scp is used by some issuers as a list
+	if scope, ok := raw["scope"].(string); ok {
+		claims.Scopes = strings.Fields(scope)
+	} else {
+		claims.Scopes = stringList(raw["scp"])
+	}
+	return claims
+}
+
+var (
+	errMalformedToken   = errors.New("malformed token")
+	errInvalidSignature = errors.New("invalid signature")
+	errExpiredToken     = errors.New("token is expired")
+	errInvalidClaims    = errors.New("invalid claims")
+)
+
+// VerifyJWT verifies the signature and claims of a compact JWS token, errors wrap ErrUnauthorized
+func VerifyJWT(token string, opts JWTOptions) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: %w", ErrUnauthorized, errMalformedToken)
+	}
+	headerBytes, err1 := decodeSegment(parts[0])
+	payload, err2 := decodeSegment(parts[1])
+	signature, err3 := decodeSegment(parts[2])
+	if err1 != nil || err2 != nil || err3 != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnauthorized, errMalformedToken)
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(headerBytes, &header); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnauthorized, errMalformedToken)
+	}
+
+	// the algorithm must match the key so a token can't pick a weaker algorithm, such as none or HS256 with a public key
+	verified := false
+	signed := []byte(parts[0] + "." + parts[1])
+	for _, key := range opts.Keys {
+		if key.Algorithm != header.Alg || (header.Kid != "" && key.ID != "" && key.ID != header.Kid) {
+			continue
+		}
+		if verifySignature(key, signed, signature) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, fmt.Errorf("%w: %w", ErrUnauthorized, errInvalidSignature)
+	}
+
+	var raw map[string]any
+	if err := json.Unmarshal(payload, &raw); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnauthorized, errMalformedToken)
+	}
+	claims := parseClaims(raw)
+
+	now := time.Now()
+	if opts.Now != nil {
+		now = opts.Now()
+	}
+	if !claims.ExpiresAt.IsZero() && !now.Before(claims.ExpiresAt.Add(opts.Leeway)) {
+		return nil, fmt.Errorf("%w: %w", ErrUnauthorized, errExpiredToken)
+	}
+	if !claims.NotBefore.IsZero() && now.Add(opts.Leeway).Before(claims.NotBefore) {
+		return nil, fmt.Errorf("%w: %w: token is not valid yet", ErrUnauthorized, errInvalidClaims)
+	}
+	if opts.Issuer != "" && claims.Issuer != opts.Issuer {
+		return nil, fmt.Errorf("%w: %w: unexpected issuer %q", ErrUnauthorized, errInvalidClaims, claims.Issuer)
+	}
+	if opts.Audience != "" {
+		found := false
+		for _, aud := range claims.Audience {
+			found = found || aud == opts.Audience
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: %w: unexpected audience", ErrUnauthorized, errInvalidClaims)
+		}
+	}
+	return claims, nil
+}
+
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// JWTMiddleware verifies the bearer token of each request, the claims are available with JWTClaims and the principal with CurrentPrincipal,
+// whose permissions are the token's scopes and roles are its roles claim
+func JWTMiddleware(opts JWTOptions) Middleware {
+	if len(opts.Keys) == 0 {
+		panic("httprouter: jwt middleware requires at least one key")
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := bearerToken(r)
+			if token == "" {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				HandleError(w, r, ErrUnauthorized)
+				return
+			}
+			claims, err := VerifyJWT(token, opts)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				HandleError(w, r, err)
+				return
+			}
+			ctx := context.WithValue(r.Context(), claimsKey, claims)
+			ctx = context.WithValue(ctx, principalKey, &Principal{
+				Subject:     claims.Subject,
+				Scheme:      "bearer",
+				Roles:       stringList(claims.Raw["roles"]),
+				Permissions: claims.Scopes,
+			})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequireScopes responds with a 403 unless the verified token has every scope, it must run after JWTMiddleware
+func RequireScopes(scopes ...string) Middleware {
+	challenge := `Bearer error="insufficient_scope", scope="` + strings.Join(scopes, " ") + `"`
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims := JWTClaims(r)
+			if claims == nil {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				HandleError(w, r, ErrUnauthorized)
+				return
+			}
+			for _, scope := range scopes {
+				if !claims.HasScope(scope) {
+					w.Header().Set("WWW-Authenticate", challenge)
+					HandleError(w, r, ErrForbidden)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package httprouter
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func signTestJWT(t *testing.T, alg string, kid string, key any, claims map[string]any) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		var err error
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func writeTestJWKS(t *testing.T, secret []byte, rsaKey *rsa.PrivateKey, ecKey *ecdsa.PrivateKey) string {
+	encode := base64.RawURLEncoding.EncodeToString
+	jwks := map[string]any{"keys": []map[string]string{
+		{"kty": "oct", "kid": "hmac", "k": encode(secret)},
+		{"kty": "RSA", "kid": "rsa", "n": encode(rsaKey.N.Bytes()), "e": encode(big.NewInt(int64(rsaKey.E)).Bytes())},
+		{"kty": "EC", "kid": "ec", "crv": "P-256", "x": encode(ecKey.X.FillBytes(make([]byte, 32))), "y": encode(ecKey.Y.FillBytes(make([]byte, 32)))},
+		{"kty": "RSA", "kid": "encryption", "use": "enc", "n": "", "e": ""},
+		{"kty": "OKP", "kid": "ed25519", "crv": "Ed25519", "x": ""},
+		{"kty": "EC", "kid": "p384", "crv": "P-384", "x": "", "y": ""},
+	}}
+	data, _ := json.Marshal(jwks)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestJWTMiddleware(t *testing.T) {
+	secret := []byte("a-very-secret-hmac-key")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := LoadJWKS(writeTestJWKS(t, secret, rsaKey, ecKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 3 {
+		t.Fatalf("Expected 3 keys but got %d", len(keys))
+	}
+
+	now := time.Unix(1700000000, 0)
+	r := NewRouter()
+	r.Use(JWTMiddleware(JWTOptions{
+		Keys:     keys,
+		Issuer:   "https://issuer.example",
+		Audience: "orders",
+		Leeway:   30 * time.Second,
+		Now:      func() time.Time { return now },
+	}))
+	r.Get("/orders", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(CurrentPrincipal(r).Subject + " " + JWTClaims(r).Raw["tenant"].(string)))
+	})
+	r.With(RequireScopes("orders:write")).Post("/orders", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("created"))
+	})
+
+	valid := func(overrides map[string]any) map[string]any {
+		claims := map[string]any{
+			"sub":    "user-1",
+			"iss":    "https://issuer.example",
+			"aud":    []string{"billing", "orders"},
+			"exp":    now.Add(time.Minute).Unix(),
+			"nbf":    now.Add(-time.Minute).Unix(),
+			"scope":  "orders:read orders:write",
+			"tenant": "acme",
+		}
+		for k, v := range overrides {
+			if v == nil {
+				delete(claims, k)
+			} else {
+				claims[k] = v
+			}
+		}
+		return claims
+	}
+
+	type Test struct {
+		method    string
+		token     string
+		status    int
+		bodyOut   string
+		challenge string
+	}
+
+	testTable := []Test{
+		{method: "GET", token: signTestJWT(t, "HS256", "hmac", secret, valid(nil)), status: http.StatusOK, bodyOut: "user-1 acme"},
+		{method: "GET", token: signTestJWT(t, "RS256", "rsa", rsaKey, valid(nil)), status: http.StatusOK, bodyOut: "user-1 acme"},
+		{method: "GET", token: signTestJWT(t, "ES256", "ec", ecKey, valid(nil)), status: http.StatusOK, bodyOut: "user-1 acme"},
+		{method: "GET", token: signTestJWT(t, "ES256", "", ecKey, valid(nil)), status: http.StatusOK, bodyOut: "user-1 acme"},
+		{method: "GET", token: signTestJWT(t, "ES256", "ec", otherKey, valid(nil)), status: http.StatusUnauthorized, bodyOut: "401 unauthorized", challenge: `Bearer error="invalid_token"`},
+		{method: "GET", token: signTestJWT(t, "HS256", "hmac", []byte("wrong"), valid(nil)), status: http.StatusUnauthorized, bodyOut: "401 unauthorized", challenge: `Bearer error="invalid_token"`},
+		{method: "GET", token: signTestJWT(t, "none", "", nil, valid(nil)), status: http.StatusUnauthorized, bodyOut: "401 unauthorized", challenge: `Bearer error="invalid_token"`},
+		{method: "GET", token: signTestJWT(t, "HS256", "rsa", secret, valid(nil)), status: http.StatusUnauthorized, bodyOut: "401 unauthorized", challenge: `Bearer error="invalid_token"`},
+		{method: "GET", token: signTestJWT(t, "HS256", "hmac", secret, valid(map[string]any{"exp": now.Add(-time.Minute).Unix()})), status: http.StatusUnauthorized, bodyOut: "401 unauthorized", challenge: `Bearer error="invalid_token"`},
+		{method: "GET", token: signTestJWT(t, "HS256", "hmac", secret, valid(map[string]any{"exp": now.Add(-10 * time.Second).Unix()})), status: http.StatusOK, bodyOut: "user-1 acme"},
+		{method: "GET", token: signTestJWT(t, "HS256", "hmac", secret, valid(map[string]any{"nbf": now.Add(time.Minute).Unix()})), status: http.StatusUnauthorized, bodyOut: "401 unauthorized", challenge: `Bearer error="invalid_token"`},
+		{method: "GET", token: signTestJWT(t, "HS256", "hmac", secret, valid(map[string]any{"nbf": now.Add(10 * time.Second).Unix()})), status: http.StatusOK, bodyOut: "user-1 acme"},
+		{method: "GET", token: signTestJWT(t, "HS256", "hmac", secret, valid(map[string]any{"iss": "https://evil.example"})), status: http.StatusUnauthorized, bodyOut: "401 unauthorized", challenge: `Bearer error="invalid_token"`},
+		{method: "GET", token: signTestJWT(t, "HS256", "hmac", secret, valid(map[string]any{"aud": "billing"})), status: http.StatusUnauthorized, bodyOut: "401 unauthorized", challenge: `Bearer error="invalid_token"`},
+		{method: "GET", token: signTestJWT(t, "HS256", "hmac", secret, valid(map[string]any{"aud": "orders"})), status: http.StatusOK, bodyOut: "user-1 acme"},
+		{method: "GET", token: "not.a.token", status: http.StatusUnauthorized, bodyOut: "401 unauthorized", challenge: `Bearer error="invalid_token"`},
+		{method: "GET", status: http.StatusUnauthorized, bodyOut: "401 unauthorized", challenge: "Bearer"},
+		{method: "POST", token: signTestJWT(t, "HS256", "hmac", secret, valid(nil)), status: http.StatusOK, bodyOut: "created"},
+		{method: "POST", token: signTestJWT(t, "HS256", "hmac", secret, valid(map[string]any{"scope": nil, "scp": []string{"orders:write"}})), status: http.StatusOK, bodyOut: "created"},
+		{method: "POST", token: signTestJWT(t, "HS256", "hmac", secret, valid(map[string]any{"scope": "orders:read"})), status: http.StatusForbidden, bodyOut: "403 forbidden", challenge: `Bearer error="insufficient_scope", scope="orders:write"`},
+	}
+
+	for i, test := range testTable {
+		req := httptest.NewRequest(test.method, "/orders", nil)
+		if test.token != "" {
+			req.Header.Set("Authorization", "Bearer "+test.token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != test.status || w.Body.String() != test.bodyOut {
+			t.Errorf("Failed test %d, expected %d %q, got %d %q", i, test.status, test.bodyOut, w.Code, w.Body.String())
+		}
+		if challenge := w.Header().Get("WWW-Authenticate"); challenge != test.challenge {
+			t.Errorf("Failed test %d, expected challenge %q but got %q", i, test.challenge, challenge)
+		}
+	}
+}
+
+func TestParseJWKSErrors(t *testing.T) {
+	type Test struct {
+		jwks string
+	}
+
+	testTable := []Test{
+		{jwks: `{"keys":[{"kty":"EC","kid":"ec","crv":"P-256","x":"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA","y":"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"}]}`},
+		{jwks: `{"keys":[{"kty":"RSA","kid":"rsa","n":"","e":"AQAB"}]}`},
+		{jwks: `{"keys":`},
+	}
+
+	for i, test := range testTable {
+		if _, err := ParseJWKS([]byte(test.jwks)); err == nil {
+			t.Errorf("Failed test %d, expected an error", i)
+		}
+	}
+}
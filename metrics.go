@@ -0,0 +1,224 @@
+package httprouter
+
+import (
+	"bytes"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	DefaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+	DefaultSizeBuckets    = []float64{100, 1000, 10000, 100000, 1000000, 10000000}
+)
+
+type MetricsOptions struct {
+	// Namespace prefixes the metric names and defaults to http
+	Namespace string
+
+	// LatencyBuckets are in seconds and SizeBuckets in bytes, they default to DefaultLatencyBuckets and DefaultSizeBuckets
+	LatencyBuckets []float64
+	SizeBuckets    []float64
+}
+
+// requests are labelled by the matched route pattern rather than the path so the number of series stays bounded
+type metricLabels struct {
+	method string
+	route  string
+	status string
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func (h *histogram) observe(buckets []float64, v float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(buckets))
+	}
+	for i, bound := range buckets {
+		if v <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+type requestMetrics struct {
+	requests uint64
+	latency  histogram
+	size     histogram
+}
+
+// Metrics collects request counts, latencies, response sizes and in flight requests for each route
+type Metrics struct {
+	opts     MetricsOptions
+	mu       sync.Mutex
+	requests map[metricLabels]*requestMetrics
+	inFlight map[metricLabels]int64
+}
+
+func NewMetrics(opts MetricsOptions) *Metrics {
+	if opts.Namespace == "" {
+		opts.Namespace = "http"
+	}
+	if opts.LatencyBuckets == nil {
+		opts.LatencyBuckets = DefaultLatencyBuckets
+	}
+	if opts.SizeBuckets == nil {
+		opts.SizeBuckets = DefaultSizeBuckets
+	}
+	return &Metrics{
+		opts:     opts,
+		requests: make(map[metricLabels]*requestMetrics),
+		inFlight: make(map[metricLabels]int64),
+	}
+}
+
+// the methods clients can send to mounts and unmatched paths are unbounded, so only the standard ones get their own series
+var metricMethods = map[string]bool{
+	"GET": true, "HEAD": true, "POST": true, "PUT": true, "PATCH": true, "DELETE": true, "OPTIONS": true, "CONNECT": true, "TRACE": true,
+}
+
+func metricMethod(r *http.Request) string {
+	if match := CurrentRoute(r); match != nil && match.Method != mountMethod {
+		// routes only match the methods they were registered with
+		return match.Method
+	}
+	if metricMethods[r.Method] {
+		return r.Method
+	}
+	return "other"
+}
+
+func statusClass(status int) string {
+	return strconv.Itoa(status/100) + "xx"
+}
+
+func (metrics *Metrics) Middleware() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := routePattern(r)
+			if route == "" {
+				// requests answered by the router itself such as preflight requests
+				route = "unmatched"
+			}
+			method := metricMethod(r)
+			flight := metricLabels{method: method, route: route}
+
+			metrics.mu.Lock()
+			metrics.inFlight[flight]++
+			metrics.mu.Unlock()
+
+			start := time.Now()
+			ww := WrapResponseWriter(w)
+			defer func() {
+				labels := metricLabels{method: method, route: route, status: statusClass(ww.Status())}
+				elapsed := time.Since(start).Seconds()
+
+				metrics.mu.Lock()
+				defer metrics.mu.Unlock()
+				metrics.inFlight[flight]--
+				rm, ok := metrics.requests[labels]
+				if !ok {
+					rm = &requestMetrics{}
+					metrics.requests[labels] = rm
+				}
+				rm.requests++
+				rm.latency.observe(metrics.opts.LatencyBuckets, elapsed)
+				rm.size.observe(metrics.opts.SizeBuckets, float64(ww.BytesWritten()))
+			}()
+			next.ServeHTTP(ww, r)
+		})
+	}
+}
+
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func (labels metricLabels) String() string {
+	s := `method="` + escapeLabel(labels.method) + `",route="` + escapeLabel(labels.route) + `"`
+	if labels.status != "" {
+		s += `,status="` + labels.status + `"`
+	}
+	return s
+}
+
+func sortedLabels[v any](series map[metricLabels]v) []metricLabels {
+	keys := make([]metricLabels, 0, len(series))
+	for labels := range series {
+		keys = append(keys, labels)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].route != keys[j].route {
+			return keys[i].route < keys[j].route
+		}
+		if keys[i].method != keys[j].method {
+			return keys[i].method < keys[j].method
+		}
+		return keys[i].status < keys[j].status
+	})
+	return keys
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func writeHistogram(w *bytes.Buffer, name string, labels metricLabels, buckets []float64, h *histogram) {
+	for i, bound := range buckets {
+		w.WriteString(name + "_bucket{" + labels.String() + `,le="` + formatFloat(bound) + `"} ` + strconv.FormatUint(h.counts[i], 10) + "\n")
+	}
+	w.WriteString(name + "_bucket{" + labels.String() + `,le="+Inf"} ` + strconv.FormatUint(h.count, 10) + "\n")
+	w.WriteString(name + "_sum{" + labels.String() + "} " + formatFloat(h.sum) + "\n")
+	w.WriteString(name + "_count{" + labels.String() + "} " + strconv.FormatUint(h.count, 10) + "\n")
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format
+func (metrics *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// rendered before writing so a slow scraper doesn't hold up requests
+	var buf bytes.Buffer
+	metrics.render(&buf)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+func (metrics *Metrics) render(buf *bytes.Buffer) {
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+
+	ns := metrics.opts.Namespace
+	requests := sortedLabels(metrics.requests)
+
+	buf.WriteString("# HELP " + ns + "_requests_total Total number of requests by route, method and status class.\n")
+	buf.WriteString("# TYPE " + ns + "_requests_total counter\n")
+	for _, labels := range requests {
+		buf.WriteString(ns + "_requests_total{" + labels.String() + "} " + strconv.FormatUint(metrics.requests[labels].requests, 10) + "\n")
+	}
+
+	buf.WriteString("# HELP " + ns + "_request_duration_seconds Time taken to serve requests.\n")
+	buf.WriteString("# TYPE " + ns + "_request_duration_seconds histogram\n")
+	for _, labels := range requests {
+		writeHistogram(buf, ns+"_request_duration_seconds", labels, metrics.opts.LatencyBuckets, &metrics.requests[labels].latency)
+	}
+
+	buf.WriteString("# HELP " + ns + "_response_size_bytes Size of response bodies.\n")
+	buf.WriteString("# TYPE " + ns + "_response_size_bytes histogram\n")
+	for _, labels := range requests {
+		writeHistogram(buf, ns+"_response_size_bytes", labels, metrics.opts.SizeBuckets, &metrics.requests[labels].size)
+	}
+
+	buf.WriteString("# HELP " + ns + "_requests_in_flight Number of requests being served.\n")
+	buf.WriteString("# TYPE " + ns + "_requests_in_flight gauge\n")
+	for _, labels := range sortedLabels(metrics.inFlight) {
+		buf.WriteString(ns + "_requests_in_flight{" + labels.String() + "} " + strconv.FormatInt(metrics.inFlight[labels], 10) + "\n")
+	}
+}
@@ -0,0 +1,126 @@
+package httprouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	metrics := NewMetrics(MetricsOptions{LatencyBuckets: []float64{0.5, 10}, SizeBuckets: []float64{5, 100}})
+
+	r := NewRouter()
+	api := r.Prefix("/api").SubRouter()
+	api.Use(metrics.Middleware())
+	api.Get("/users", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("users"))
+	})
+	api.Post("/users", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": "a-new-user"}`))
+	})
+	api.Get("/fail", func(w http.ResponseWriter, r *http.Request) {
+		HandleError(w, r, ErrInternal)
+	})
+	api.Get("/busy", func(w http.ResponseWriter, r *http.Request) {
+		rec := httptest.NewRecorder()
+		metrics.ServeHTTP(rec, r)
+		w.Write(rec.Body.Bytes())
+	})
+	admin := r.Prefix("/admin").SubRouter()
+	admin.Get("/metrics", metrics.ServeHTTP)
+
+	requests := []struct {
+		method string
+		url    string
+	}{
+		{"GET", "/api/users"},
+		{"GET", "/api/users?page=2"},
+		{"POST", "/api/users"},
+		{"GET", "/api/fail"},
+		{"GET", "/api/missing"},
+	}
+	for _, req := range requests {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(req.method, req.url, nil))
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/busy", nil))
+	if !strings.Contains(w.Body.String(), `http_requests_in_flight{method="GET",route="/api/busy"} 1`) {
+		t.Errorf("Expected the request to be in flight while it is served but got\n%s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/admin/metrics", nil))
+	body := w.Body.String()
+
+	if w.Header().Get("Content-Type") != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Errorf("Expected the Prometheus text content type but got %q", w.Header().Get("Content-Type"))
+	}
+
+	expected := []string{
+		"# TYPE http_requests_total counter",
+		`http_requests_total{method="GET",route="/api/users",status="2xx"} 2`,
+		`http_requests_total{method="POST",route="/api/users",status="2xx"} 1`,
+		`http_requests_total{method="GET",route="/api/fail",status="5xx"} 1`,
+		"# TYPE http_request_duration_seconds histogram",
+		`http_request_duration_seconds_bucket{method="GET",route="/api/users",status="2xx",le="10"} 2`,
+		`http_request_duration_seconds_bucket{method="GET",route="/api/users",status="2xx",le="+Inf"} 2`,
+		`http_request_duration_seconds_count{method="GET",route="/api/users",status="2xx"} 2`,
+		"# TYPE http_response_size_bytes histogram",
+		`http_response_size_bytes_bucket{method="GET",route="/api/users",status="2xx",le="5"} 2`,
+		`http_response_size_bytes_bucket{method="POST",route="/api/users",status="2xx",le="5"} 0`,
+		`http_response_size_bytes_bucket{method="POST",route="/api/users",status="2xx",le="100"} 1`,
+		`http_response_size_bytes_sum{method="GET",route="/api/users",status="2xx"} 10`,
+		"# TYPE http_requests_in_flight gauge",
+		`http_requests_in_flight{method="GET",route="/api/busy"} 0`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Expected the metrics to contain %q but got\n%s", line, body)
+		}
+	}
+	if strings.Contains(body, "page=2") || strings.Contains(body, "/api/missing") || strings.Contains(body, "/admin/metrics") {
+		t.Errorf("Expected only matched route patterns of the api to be labelled but got\n%s", body)
+	}
+}
+
+func TestMetricsLabelEscaping(t *testing.T) {
+	labels := metricLabels{method: "GET", route: "/a\"b\\c\nd", status: "2xx"}
+	if labels.String() != `method="GET",route="/a\"b\\c\nd",status="2xx"` {
+		t.Errorf("Expected escaped labels but got %s", labels.String())
+	}
+}
+
+func TestMetricsMethodLabels(t *testing.T) {
+	metrics := NewMetrics(MetricsOptions{})
+
+	r := NewRouter()
+	r.Use(metrics.Middleware())
+	r.Route("PROPFIND", "/files", func(w http.ResponseWriter, r *http.Request) {})
+	r.Mount("/static", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, method := range []string{"GET", "FOO", "BAR", "BAZ"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/static/app.js", nil))
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PROPFIND", "/files", nil))
+
+	w := httptest.NewRecorder()
+	metrics.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+
+	expected := []string{
+		`http_requests_total{method="GET",route="/static",status="2xx"} 1`,
+		`http_requests_total{method="other",route="/static",status="2xx"} 3`,
+		`http_requests_total{method="PROPFIND",route="/files",status="2xx"} 1`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(body, line) {
+			t.Errorf("Expected %s in\n%s", line, body)
+		}
+	}
+	if strings.Contains(body, `method="FOO"`) {
+		t.Errorf("Expected unknown methods to share the other label but got\n%s", body)
+	}
+}
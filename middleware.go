@@ -1,8 +1,11 @@
 package httprouter
 
 import (
+	"fmt"
 	"log"
 	"net/http"
+	"runtime/debug"
+	"time"
 )
 
 type Middleware = func(next http.Handler) http.Handler
@@ -10,28 +13,56 @@ type Middleware = func(next http.Handler) http.Handler
 func LoggerMiddleware(logger *log.Logger) Middleware {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rw := WrapResponseWriter(w)
+			next.ServeHTTP(rw, r)
 			logger.Printf(
-				"Header %s, Method %s, Path %s",
-				r.Header,
+				"Header %s, Method %s, Path %s, Status %d, Bytes %d, Duration %s",
+				redactHeaders(r.Header, defaultRedactedHeaders),
 				r.Method,
 				r.URL.EscapedPath(),
+				rw.Status(),
+				rw.BytesWritten(),
+				time.Since(start),
 			)
-			next.ServeHTTP(w, r)
 		})
 	}
 }
 
-func CorsMiddleware() Middleware {
+func RecoveryMiddleware(logger *log.Logger) Middleware {
+	if logger == nil {
+		logger = log.Default()
+	}
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Access-Control-Allow-Origin", "*")
-			if r.Method == "OPTIONS" {
-				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-				w.WriteHeader(http.StatusNoContent)
-				return
-			}
-			next.ServeHTTP(w, r)
+			rw := WrapResponseWriter(w)
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				if rec == http.ErrAbortHandler {
+					// the handler deliberately aborted the response so let net/http handle it silently
+					panic(rec)
+				}
+
+				logger.Printf(
+					"Panic %v, Method %s, Path %s, Route %s, Request ID %s\n%s",
+					rec,
+					r.Method,
+					r.URL.EscapedPath(),
+					routePattern(r),
+					RequestID(r),
+					debug.Stack(),
+				)
+
+				if rw.Written() {
+					// a partial response was already sent so abort the connection rather than let the client treat it as complete
+					panic(http.ErrAbortHandler)
+				}
+				HandleError(w, r, &HTTPError{Status: http.StatusInternalServerError, Err: fmt.Errorf("panic: %v", rec)})
+			}()
+			next.ServeHTTP(rw, r)
 		})
 	}
 }
@@ -0,0 +1,242 @@
+package httprouter
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecoveryMiddleware(t *testing.T) {
+	var logs bytes.Buffer
+	r := NewRouter()
+	r.Use(RecoveryMiddleware(log.New(&logs, "", 0)))
+
+	r.Get("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("something went wrong")
+	})
+	r.Get("/panic/written", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("Partial"))
+		panic("something went wrong")
+	})
+	r.Get("/abort", func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/panic", nil))
+
+	if w.Code != http.StatusInternalServerError || w.Body.String() != "500 internal server error" {
+		t.Errorf("Expected a 500 response for a panic before WriteHeader but got %d %q", w.Code, w.Body.String())
+	}
+	logged := logs.String()
+	if !strings.Contains(logged, "Panic something went wrong, Method GET, Path /panic, Route /panic, Request ID") {
+		t.Errorf("Expected the panic to be logged with the request but got %s", logged)
+	}
+	if !strings.Contains(logged, "goroutine") {
+		t.Errorf("Expected the stack to be logged but got %s", logged)
+	}
+
+	type Test struct {
+		url     string
+		status  int
+		bodyOut string
+	}
+
+	testTable := []Test{
+		{url: "/panic/written", status: http.StatusAccepted, bodyOut: "Partial"},
+		{url: "/abort", status: http.StatusOK, bodyOut: ""},
+	}
+
+	for i, test := range testTable {
+		w := httptest.NewRecorder()
+		func() {
+			defer func() {
+				if rec := recover(); rec != http.ErrAbortHandler {
+					t.Errorf("Failed test %d, expected the response to be aborted but recovered %v", i, rec)
+				}
+			}()
+			r.ServeHTTP(w, httptest.NewRequest("GET", test.url, nil))
+		}()
+
+		if w.Code != test.status || w.Body.String() != test.bodyOut {
+			t.Errorf("Failed test %d, expected %d %q, got %d %q", i, test.status, test.bodyOut, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	type Test struct {
+		opts     RequestIDOptions
+		header   string
+		incoming string
+		expected string
+	}
+
+	generator := func() string { return "generated" }
+
+	testTable := []Test{
+		{opts: RequestIDOptions{Generator: generator}, header: "X-Request-ID", incoming: "", expected: "generated"},
+		{opts: RequestIDOptions{Generator: generator}, header: "X-Request-ID", incoming: "abc-123", expected: "abc-123"},
+		{opts: RequestIDOptions{Generator: generator}, header: "X-Request-ID", incoming: "abc 123\n", expected: "generated"},
+		{opts: RequestIDOptions{Generator: generator}, header: "X-Request-ID", incoming: strings.Repeat("a", 65), expected: "generated"},
+		{opts: RequestIDOptions{Generator: generator, MaxLength: 8}, header: "X-Request-ID", incoming: "123456789", expected: "generated"},
+		{opts: RequestIDOptions{Generator: generator, Header: "X-Correlation-ID"}, header: "X-Correlation-ID", incoming: "corr", expected: "corr"},
+	}
+
+	for i, test := range testTable {
+		var inHandler string
+		handler := RequestIDMiddleware(test.opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			inHandler = RequestID(r)
+		}))
+
+		req := httptest.NewRequest("GET", "/", nil)
+		if test.incoming != "" {
+			req.Header.Set(test.header, test.incoming)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if inHandler != test.expected || w.Header().Get(test.header) != test.expected {
+			t.Errorf("Failed test %d, expected id %q but got %q in the handler and %q in the response",
+				i, test.expected, inHandler, w.Header().Get(test.header))
+		}
+	}
+
+	id := newRequestID()
+	if !validRequestID(id, 64) || len(id) != 32 {
+		t.Errorf("Expected the generated id %q to be valid", id)
+	}
+}
+
+func TestRequestIDPropagation(t *testing.T) {
+	var logs bytes.Buffer
+	r := NewRouter()
+	r.Use(AccessLogMiddleware(AccessLogOptions{Logger: slog.New(slog.NewTextHandler(&logs, nil))}))
+	r.Use(RecoveryMiddleware(log.New(&logs, "", 0)))
+	r.Use(RequestIDMiddleware(RequestIDOptions{}))
+	r.Get("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("something went wrong")
+	})
+
+	req := httptest.NewRequest("GET", "/panic", nil)
+	req.Header.Set("X-Request-ID", "req-42")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	if RequestID(req) != "" {
+		t.Errorf("Expected the caller's request to be left unchanged")
+	}
+
+	logged := logs.String()
+	if !strings.Contains(logged, "Request ID req-42") || !strings.Contains(logged, "request_id=req-42") {
+		t.Errorf("Expected the request id to be logged by the recovery and access log middlewares but got %s", logged)
+	}
+}
+
+func TestTimeoutMiddleware(t *testing.T) {
+	var lateWriteErr error
+	slowServed := make(chan struct{})
+	lateWriteDone := make(chan struct{})
+
+	r := NewRouter()
+	r.Use(TimeoutMiddleware(20 * time.Millisecond))
+	r.Get("/fast", func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Deadline(); !ok {
+			t.Errorf("Expected the request context to have a deadline")
+		}
+		w.Header().Set("X-Fast", "true")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("Fast"))
+	})
+	r.Get("/slow", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Partial"))
+		<-slowServed
+		_, lateWriteErr = w.Write([]byte("Late"))
+		close(lateWriteDone)
+	})
+
+	sr := r.Prefix("/uploads").SubRouter()
+	sr.With(TimeoutMiddleware(time.Millisecond)).Get("/slow", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	late := func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		w.Write([]byte("late"))
+	}
+	sr.Get("/hand", TimeoutMiddleware(time.Millisecond)(http.HandlerFunc(late)).ServeHTTP)
+	sr.With(TimeoutMiddleware(time.Second)).Get("/large", func(w http.ResponseWriter, r *http.Request) {
+		deadline, _ := r.Context().Deadline()
+		if time.Until(deadline) < 500*time.Millisecond {
+			t.Errorf("Expected the route's timeout to replace the router's")
+		}
+		time.Sleep(60 * time.Millisecond)
+		w.Write([]byte("Uploaded"))
+	})
+
+	type Test struct {
+		url     string
+		status  int
+		bodyOut string
+	}
+
+	testTable := []Test{
+		{url: "/fast", status: http.StatusCreated, bodyOut: "Fast"},
+		{url: "/slow", status: http.StatusServiceUnavailable, bodyOut: "503 service unavailable"},
+		{url: "/uploads/slow", status: http.StatusServiceUnavailable, bodyOut: "503 service unavailable"},
+		{url: "/uploads/large", status: http.StatusOK, bodyOut: "Uploaded"},
+		{url: "/uploads/hand", status: http.StatusServiceUnavailable, bodyOut: "503 service unavailable"},
+	}
+
+	for i, test := range testTable {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", test.url, nil))
+		if test.url == "/slow" {
+			close(slowServed)
+		}
+
+		if w.Code != test.status || w.Body.String() != test.bodyOut {
+			t.Errorf("Failed test %d, expected %d %q, got %d %q", i, test.status, test.bodyOut, w.Code, w.Body.String())
+		}
+		if test.url == "/fast" && w.Header().Get("X-Fast") != "true" {
+			t.Errorf("Expected the buffered headers to be copied to the response")
+		}
+	}
+
+	<-lateWriteDone
+	if lateWriteErr != http.ErrHandlerTimeout {
+		t.Errorf("Expected writes after the timeout to fail with ErrHandlerTimeout but got %v", lateWriteErr)
+	}
+
+	var handled error
+	r.ErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+		handled = err
+		w.WriteHeader(http.StatusGatewayTimeout)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/uploads/slow", nil))
+	if !errors.Is(handled, ErrTimeout) || w.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected the error handler to receive ErrTimeout but got %v", handled)
+	}
+}
+
+func TestTimeoutExternalRouter(t *testing.T) {
+	inner := NewRouter()
+	sr := SubRouterBuilder{parent: &recordingRouter{Router: inner}}.SubRouter()
+	sr.With(TimeoutMiddleware(time.Millisecond)).Get("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		w.Write([]byte("late"))
+	})
+
+	w := httptest.NewRecorder()
+	inner.ServeHTTP(w, httptest.NewRequest("GET", "/slow", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected the timeout to apply to a route registered through an external router but got %d %q", w.Code, w.Body.String())
+	}
+}
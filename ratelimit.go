@@ -0,0 +1,245 @@
+package httprouter
+
+import (
+	"hash/fnv"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type RateLimitAlgorithm int
+
+const (
+	TokenBucket RateLimitAlgorithm = iota
+	SlidingWindow
+)
+
+type KeyFunc = func(r *http.Request) string
+
+// RateLimitState holds the state of both algorithms so stores don't need to know which one is in use
+type RateLimitState struct {
+	Tokens   float64
+	Last     time.Time
+	Window   time.Time
+	Current  int
+	Previous int
+}
+
+type RateLimitStore interface {
+	// Update atomically applies fn to the state stored for the key, starting from a zero state if there is none.
+	// State that isn't updated for ttl may be discarded
+	Update(key string, ttl time.Duration, fn func(state *RateLimitState))
+}
+
+type RateLimitOptions struct {
+	// Limit is the number of requests allowed per Window for each key
+	Limit  int
+	Window time.Duration
+
+	Algorithm RateLimitAlgorithm
+
+	// Key chooses the bucket for a request, defaults to KeyByIP
+	Key KeyFunc
+
+	// Store defaults to a MemoryRateLimitStore
+	Store RateLimitStore
+}
+
+func KeyByIP(r *http.Request) string {
+	return ClientIP(r)
+}
+
+func KeyByRoute(r *http.Request) string {
+	return r.Method + " " + routePattern(r)
+}
+
+func KeyByHeader(name string) KeyFunc {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// CombineKeys limits each combination of keys separately, such as each client on each route
+func CombineKeys(keys ...KeyFunc) KeyFunc {
+	return func(r *http.Request) string {
+		parts := make([]string, len(keys))
+		for i, key := range keys {
+			parts[i] = key(r)
+		}
+		return strings.Join(parts, "|")
+	}
+}
+
+type rateLimitResult struct {
+	allowed    bool
+	remaining  int
+	reset      time.Duration
+	retryAfter time.Duration
+}
+
+func takeTokenBucket(state *RateLimitState, limit int, window time.Duration, now time.Time) rateLimitResult {
+	rate := float64(limit) / window.Seconds()
+	if state.Last.IsZero() {
+		state.Tokens = float64(limit)
+	} else {
+		state.Tokens = math.Min(float64(limit), state.Tokens+now.Sub(state.Last).Seconds()*rate)
+	}
+	state.Last = now
+
+	result := rateLimitResult{}
+	if state.Tokens >= 1 {
+		state.Tokens -= 1
+		result.allowed = true
+	} else {
+		result.retryAfter = time.Duration((1 - state.Tokens) / rate * float64(time.Second))
+	}
+	result.remaining = int(state.Tokens)
+	result.reset = time.Duration((float64(limit) - state.Tokens) / rate * float64(time.Second))
+	return result
+}
+
+// approximates a sliding window by weighting the count of the previous fixed window by how much of it overlaps
+func takeSlidingWindow(state *RateLimitState, limit int, window time.Duration, now time.Time) rateLimitResult {
+	start := now.Truncate(window)
+	if !state.Window.Equal(start) {
+		if state.Window.Add(window).Equal(start) {
+			state.Previous = state.Current
+		} else {
+			state.Previous = 0
+		}
+		state.Current = 0
+		state.Window = start
+	}
+
+	elapsed := now.Sub(start)
+	weight := 1 - float64(elapsed)/float64(window)
+	estimate := float64(state.Previous)*weight + float64(state.Current)
+
+	result := rateLimitResult{reset: window - elapsed}
+	if estimate+1 <= float64(limit) {
+		state.Current += 1
+		estimate += 1
+		result.allowed = true
+	} else {
+		result.retryAfter = window - elapsed
+	}
+	result.remaining = int(math.Max(0, float64(limit)-math.Ceil(estimate)))
+	return result
+}
+
+func RateLimitMiddleware(opts RateLimitOptions) Middleware {
+	if opts.Limit <= 0 || opts.Window <= 0 {
+		panic("httprouter: rate limit requires a positive limit and window")
+	}
+	if opts.Key == nil {
+		opts.Key = KeyByIP
+	}
+	if opts.Store == nil {
+		opts.Store = NewMemoryRateLimitStore(32)
+	}
+	take := takeTokenBucket
+	if opts.Algorithm == SlidingWindow {
+		take = takeSlidingWindow
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var result rateLimitResult
+			now := time.Now()
+			opts.Store.Update(opts.Key(r), 2*opts.Window, func(state *RateLimitState) {
+				result = take(state, opts.Limit, opts.Window, now)
+			})
+
+			header := w.Header()
+			header.Set("RateLimit-Limit", strconv.Itoa(opts.Limit))
+			header.Set("RateLimit-Remaining", strconv.Itoa(result.remaining))
+			header.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.reset)))
+
+			if !result.allowed {
+				header.Set("Retry-After", strconv.Itoa(ceilSeconds(result.retryAfter)))
+				HandleError(w, r, ErrRateLimited)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+type rateLimitEntry struct {
+	state   RateLimitState
+	expires time.Time
+}
+
+type rateLimitShard struct {
+	mu        sync.Mutex
+	entries   map[string]*rateLimitEntry
+	lastSweep time.Time
+}
+
+// MemoryRateLimitStore keeps state in memory split across shards so keys in different shards don't contend
+type MemoryRateLimitStore struct {
+	shards []rateLimitShard
+}
+
+const rateLimitSweepInterval = time.Minute
+
+func NewMemoryRateLimitStore(shards int) *MemoryRateLimitStore {
+	if shards <= 0 {
+		shards = 1
+	}
+	store := &MemoryRateLimitStore{shards: make([]rateLimitShard, shards)}
+	for i := range store.shards {
+		store.shards[i].entries = make(map[string]*rateLimitEntry)
+	}
+	return store
+}
+
+func (store *MemoryRateLimitStore) Update(key string, ttl time.Duration, fn func(state *RateLimitState)) {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	shard := &store.shards[h.Sum32()%uint32(len(store.shards))]
+
+	now := time.Now()
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if now.Sub(shard.lastSweep) > rateLimitSweepInterval {
+		shard.sweep(now)
+	}
+
+	entry, ok := shard.entries[key]
+	if !ok || now.After(entry.expires) {
+		entry = &rateLimitEntry{}
+		shard.entries[key] = entry
+	}
+	fn(&entry.state)
+	entry.expires = now.Add(ttl)
+}
+
+func (shard *rateLimitShard) sweep(now time.Time) {
+	for key, entry := range shard.entries {
+		if now.After(entry.expires) {
+			delete(shard.entries, key)
+		}
+	}
+	shard.lastSweep = now
+}
+
+func (store *MemoryRateLimitStore) Len() int {
+	count := 0
+	for i := range store.shards {
+		shard := &store.shards[i]
+		shard.mu.Lock()
+		count += len(shard.entries)
+		shard.mu.Unlock()
+	}
+	return count
+}
@@ -0,0 +1,175 @@
+package httprouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	state := RateLimitState{}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	type Test struct {
+		at        time.Duration
+		allowed   bool
+		remaining int
+	}
+
+	// 2 requests per second, so a token is refilled every 500ms
+	testTable := []Test{
+		{at: 0, allowed: true, remaining: 1},
+		{at: 100 * time.Millisecond, allowed: true, remaining: 0},
+		{at: 200 * time.Millisecond, allowed: false, remaining: 0},
+		{at: 700 * time.Millisecond, allowed: true, remaining: 0},
+		{at: 3 * time.Second, allowed: true, remaining: 1},
+	}
+
+	for i, test := range testTable {
+		result := takeTokenBucket(&state, 2, time.Second, start.Add(test.at))
+		if result.allowed != test.allowed || result.remaining != test.remaining {
+			t.Errorf("Failed test %d, expected allowed %v and remaining %d but got %v and %d",
+				i, test.allowed, test.remaining, result.allowed, result.remaining)
+		}
+		if !result.allowed && result.retryAfter <= 0 {
+			t.Errorf("Failed test %d, expected a retry after duration for a denied request", i)
+		}
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	state := RateLimitState{}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	type Test struct {
+		at        time.Duration
+		allowed   bool
+		remaining int
+	}
+
+	// 4 requests per minute
+	testTable := []Test{
+		{at: 0, allowed: true, remaining: 3},
+		{at: 10 * time.Second, allowed: true, remaining: 2},
+		{at: 20 * time.Second, allowed: true, remaining: 1},
+		{at: 30 * time.Second, allowed: true, remaining: 0},
+		{at: 50 * time.Second, allowed: false, remaining: 0},
+		// the previous window's 4 requests are weighted 0.75
+		{at: 75 * time.Second, allowed: true, remaining: 0},
+		{at: 80 * time.Second, allowed: false, remaining: 0},
+		// the previous window's 1 request is weighted 0.5
+		{at: 150 * time.Second, allowed: true, remaining: 2},
+		// windows more than one apart don't carry over
+		{at: 300 * time.Second, allowed: true, remaining: 3},
+	}
+
+	for i, test := range testTable {
+		result := takeSlidingWindow(&state, 4, time.Minute, start.Add(test.at))
+		if result.allowed != test.allowed || result.remaining != test.remaining {
+			t.Errorf("Failed test %d, expected allowed %v and remaining %d but got %v and %d",
+				i, test.allowed, test.remaining, result.allowed, result.remaining)
+		}
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	r := NewRouter()
+	r.Use(RateLimitMiddleware(RateLimitOptions{
+		Limit:     2,
+		Window:    time.Hour,
+		Algorithm: SlidingWindow,
+		Key:       CombineKeys(KeyByHeader("X-Api-Key"), KeyByRoute),
+	}))
+	r.Get("/products", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Products"))
+	})
+	r.Get("/articles", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Articles"))
+	})
+
+	type Test struct {
+		url       string
+		key       string
+		status    int
+		remaining string
+	}
+
+	testTable := []Test{
+		{url: "/products", key: "a", status: http.StatusOK, remaining: "1"},
+		{url: "/products", key: "a", status: http.StatusOK, remaining: "0"},
+		{url: "/products", key: "a", status: http.StatusTooManyRequests, remaining: "0"},
+		{url: "/products", key: "b", status: http.StatusOK, remaining: "1"},
+		{url: "/articles", key: "a", status: http.StatusOK, remaining: "1"},
+	}
+
+	for i, test := range testTable {
+		req := httptest.NewRequest("GET", test.url, nil)
+		req.Header.Set("X-Api-Key", test.key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != test.status || w.Header().Get("RateLimit-Remaining") != test.remaining {
+			t.Errorf("Failed test %d, expected %d with %s remaining but got %d with %s remaining",
+				i, test.status, test.remaining, w.Code, w.Header().Get("RateLimit-Remaining"))
+		}
+		if w.Header().Get("RateLimit-Limit") != "2" || w.Header().Get("RateLimit-Reset") == "" {
+			t.Errorf("Failed test %d, expected the RateLimit headers to be set but got %v", i, w.Header())
+		}
+		if test.status == http.StatusTooManyRequests {
+			if w.Header().Get("Retry-After") == "" || w.Body.String() != "429 too many requests" {
+				t.Errorf("Failed test %d, expected a Retry-After header and 429 body but got %v %q", i, w.Header(), w.Body.String())
+			}
+		}
+	}
+}
+
+func TestMemoryRateLimitStoreExpiry(t *testing.T) {
+	store := NewMemoryRateLimitStore(4)
+
+	store.Update("a", time.Millisecond, func(state *RateLimitState) {
+		state.Current = 5
+	})
+	store.Update("b", time.Hour, func(state *RateLimitState) {
+		state.Current = 5
+	})
+	time.Sleep(5 * time.Millisecond)
+
+	store.Update("a", time.Hour, func(state *RateLimitState) {
+		if state.Current != 0 {
+			t.Errorf("Expected expired state to be discarded but got %d", state.Current)
+		}
+	})
+	store.Update("b", time.Hour, func(state *RateLimitState) {
+		if state.Current != 5 {
+			t.Errorf("Expected unexpired state to be kept but got %d", state.Current)
+		}
+	})
+
+	for i := range store.shards {
+		store.shards[i].sweep(time.Now().Add(2 * time.Hour))
+	}
+	if store.Len() != 0 {
+		t.Errorf("Expected a sweep to remove expired entries but %d remain", store.Len())
+	}
+}
+
+func TestRateLimitInvalidOptions(t *testing.T) {
+	testTable := []RateLimitOptions{
+		{},
+		{Limit: 10},
+		{Window: time.Minute},
+		{Limit: -1, Window: time.Minute},
+	}
+
+	for i, opts := range testTable {
+		func() {
+			defer func() {
+				if rec := recover(); rec == nil {
+					t.Errorf("Failed test %d, expected %+v to panic", i, opts)
+				}
+			}()
+			RateLimitMiddleware(opts)
+		}()
+	}
+}
@@ -0,0 +1,236 @@
+package httprouter
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+type clientInfo struct {
+	ip     string
+	scheme string
+	host   string
+}
+
+func parsePrefixes(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			addr, err := netip.ParseAddr(cidr)
+			if err != nil {
+				return nil, err
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, err
+		}
+		if prefix.Addr().Is4In6() {
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parses an address from a forwarding header which may have a port, brackets or quotes
+func parseForwardedAddr(value string) (netip.Addr, bool) {
+	value = strings.Trim(strings.TrimSpace(value), "\"")
+	if addrPort, err := netip.ParseAddrPort(value); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(value, "["), "]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+type forwardedHop struct {
+	addr    netip.Addr
+	valid   bool
+	proto   string
+	host    string
+	present bool
+}
+
+func parseForwarded(values []string) []forwardedHop {
+	hops := make([]forwardedHop, 0)
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			hop := forwardedHop{}
+			for _, pair := range strings.Split(element, ";") {
+				key, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok {
+					continue
+				}
+				val = strings.Trim(val, "\"")
+				switch strings.ToLower(key) {
+				case "for":
+					hop.addr, hop.valid = parseForwardedAddr(val)
+					hop.present = true
+				case "proto":
+					hop.proto = strings.ToLower(val)
+				case "host":
+					hop.host = val
+				}
+			}
+			if hop.present {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	return hops
+}
+
+// walks the hops from the closest to the furthest, returning the first hop that isn't a trusted proxy
+func untrustedHop(hops []forwardedHop, trusted []netip.Prefix) (forwardedHop, bool) {
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := hops[i]
+		if !hop.valid {
+			// an obfuscated or unknown address can't be trusted or walked past
+			return hop, false
+		}
+		if !containsAddr(trusted, hop.addr) || i == 0 {
+			return hop, true
+		}
+	}
+	return forwardedHop{}, false
+}
+
+func resolveClient(r *http.Request, trusted []netip.Prefix) clientInfo {
+	info := clientInfo{ip: remoteIP(r), scheme: "http", host: r.Host}
+	if r.TLS != nil {
+		info.scheme = "https"
+	}
+
+	peer, ok := parseForwardedAddr(info.ip)
+	if !ok || !containsAddr(trusted, peer) {
+		return info
+	}
+
+	var hop forwardedHop
+	ok = false
+	if forwarded := r.Header.Values("Forwarded"); len(forwarded) > 0 {
+		hops := parseForwarded(forwarded)
+		hop, ok = untrustedHop(hops, trusted)
+		if len(hops) > 0 {
+			// the scheme and host are taken from the hop added by our own proxy
+			hop.proto = hops[len(hops)-1].proto
+			hop.host = hops[len(hops)-1].host
+		}
+	} else if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := make([]forwardedHop, 0)
+		for _, value := range xff {
+			for _, part := range strings.Split(value, ",") {
+				addr, valid := parseForwardedAddr(part)
+				hops = append(hops, forwardedHop{addr: addr, valid: valid, present: true})
+			}
+		}
+		hop, ok = untrustedHop(hops, trusted)
+		hop.proto = strings.ToLower(lastListValue(r.Header.Get("X-Forwarded-Proto")))
+		hop.host = lastListValue(r.Header.Get("X-Forwarded-Host"))
+	} else if realIP, valid := parseForwardedAddr(r.Header.Get("X-Real-IP")); valid {
+		hop, ok = forwardedHop{addr: realIP, valid: true}, true
+		hop.proto = strings.ToLower(r.Header.Get("X-Forwarded-Proto"))
+	}
+
+	if ok {
+		info.ip = hop.addr.String()
+	}
+	if hop.proto == "http" || hop.proto == "https" {
+		info.scheme = hop.proto
+	}
+	if hop.host != "" {
+		info.host = hop.host
+	}
+	return info
+}
+
+func lastListValue(value string) string {
+	parts := strings.Split(value, ",")
+	return strings.TrimSpace(parts[len(parts)-1])
+}
+
+// RealIPMiddleware resolves the client address, scheme and host from the Forwarded, X-Forwarded-For,
+// X-Forwarded-Proto, X-Forwarded-Host and X-Real-IP headers. The headers are only used when the immediate peer is in
+// one of the trusted proxy CIDRs, and the chain is walked from the right until an untrusted address is found
+func RealIPMiddleware(trustedProxies ...string) Middleware {
+	trusted, err := parsePrefixes(trustedProxies)
+	if err != nil {
+		panic("httprouter: invalid trusted proxy: " + err.Error())
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			info := resolveClient(r, trusted)
+			if state := stateOf(r); state != nil {
+				state.mu.Lock()
+				state.client = &info
+				state.mu.Unlock()
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientInfoKey, info)))
+		})
+	}
+}
+
+// middlewares added to the router before RealIPMiddleware find the client once it has run
+func clientInfoOf(r *http.Request) (clientInfo, bool) {
+	if info, ok := r.Context().Value(clientInfoKey).(clientInfo); ok {
+		return info, true
+	}
+	if state := stateOf(r); state != nil {
+		state.mu.Lock()
+		defer state.mu.Unlock()
+		if state.client != nil {
+			return *state.client, true
+		}
+	}
+	return clientInfo{}, false
+}
+
+// ClientIP is the address resolved by RealIPMiddleware or the address of the immediate peer if it wasn't used
+func ClientIP(r *http.Request) string {
+	if info, ok := clientInfoOf(r); ok {
+		return info.ip
+	}
+	return remoteIP(r)
+}
+
+func RequestScheme(r *http.Request) string {
+	if info, ok := clientInfoOf(r); ok {
+		return info.scheme
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+func RequestHost(r *http.Request) string {
+	if info, ok := clientInfoOf(r); ok {
+		return info.host
+	}
+	return r.Host
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,121 @@
+package httprouter
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRealIPMiddleware(t *testing.T) {
+	type Test struct {
+		name       string
+		remoteAddr string
+		headers    map[string][]string
+		ip         string
+		scheme     string
+		host       string
+	}
+
+	testTable := []Test{
+		{name: "untrusted peer is used as is", remoteAddr: "203.0.113.9:4000",
+			headers: map[string][]string{"X-Forwarded-For": {"198.51.100.1"}, "X-Forwarded-Proto": {"https"}},
+			ip:      "203.0.113.9", scheme: "http", host: "example.com"},
+		{name: "trusted peer without headers", remoteAddr: "10.0.0.1:4000",
+			ip: "10.0.0.1", scheme: "http", host: "example.com"},
+		{name: "x-forwarded-for single hop", remoteAddr: "10.0.0.1:4000",
+			headers: map[string][]string{
+				"X-Forwarded-For":   {"198.51.100.1"},
+				"X-Forwarded-Proto": {"https"},
+				"X-Forwarded-Host":  {"api.example.com"},
+			},
+			ip: "198.51.100.1", scheme: "https", host: "api.example.com"},
+		{name: "x-forwarded-for skips trusted hops from the right", remoteAddr: "10.0.0.1:4000",
+			headers: map[string][]string{"X-Forwarded-For": {"192.0.2.66, 198.51.100.1", "10.0.0.7"}},
+			ip:      "198.51.100.1", scheme: "http", host: "example.com"},
+		{name: "x-forwarded-for spoofed left values are ignored", remoteAddr: "10.0.0.1:4000",
+			headers: map[string][]string{"X-Forwarded-For": {"10.0.0.9, 198.51.100.1"}},
+			ip:      "198.51.100.1", scheme: "http", host: "example.com"},
+		{name: "x-forwarded-for with only trusted hops", remoteAddr: "10.0.0.1:4000",
+			headers: map[string][]string{"X-Forwarded-For": {"10.0.0.3, 10.0.0.2"}},
+			ip:      "10.0.0.3", scheme: "http", host: "example.com"},
+		{name: "x-forwarded-for invalid hop stops the walk", remoteAddr: "10.0.0.1:4000",
+			headers: map[string][]string{"X-Forwarded-For": {"198.51.100.1, garbage"}},
+			ip:      "10.0.0.1", scheme: "http", host: "example.com"},
+		{name: "forwarded header", remoteAddr: "10.0.0.1:4000",
+			headers: map[string][]string{"Forwarded": {`for="[2001:db8::1]:4711";proto=https;host=shop.example.com`}},
+			ip:      "2001:db8::1", scheme: "https", host: "shop.example.com"},
+		{name: "forwarded header takes precedence with multiple hops", remoteAddr: "10.0.0.1:4000",
+			headers: map[string][]string{
+				"Forwarded":       {"for=192.0.2.60;proto=http, for=198.51.100.17;proto=https;by=10.0.0.5"},
+				"X-Forwarded-For": {"192.0.2.99"},
+			},
+			ip: "198.51.100.17", scheme: "https", host: "example.com"},
+		{name: "forwarded unknown address", remoteAddr: "10.0.0.1:4000",
+			headers: map[string][]string{"Forwarded": {"for=unknown;proto=https"}},
+			ip:      "10.0.0.1", scheme: "https", host: "example.com"},
+		{name: "x-real-ip", remoteAddr: "10.0.0.1:4000",
+			headers: map[string][]string{"X-Real-Ip": {"198.51.100.5"}},
+			ip:      "198.51.100.5", scheme: "http", host: "example.com"},
+		{name: "ipv6 trusted proxy", remoteAddr: "[fd00::1]:4000",
+			headers: map[string][]string{"X-Forwarded-For": {"198.51.100.1"}},
+			ip:      "198.51.100.1", scheme: "http", host: "example.com"},
+		{name: "ipv4 mapped ipv6 peer", remoteAddr: "[::ffff:10.0.0.1]:4000",
+			headers: map[string][]string{"X-Forwarded-For": {"198.51.100.1"}},
+			ip:      "198.51.100.1", scheme: "http", host: "example.com"},
+	}
+
+	middleware := RealIPMiddleware("10.0.0.0/8", "fd00::/8")
+
+	for _, test := range testTable {
+		var ip, scheme, host string
+		handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip, scheme, host = ClientIP(r), RequestScheme(r), RequestHost(r)
+		}))
+
+		req := httptest.NewRequest("GET", "http://example.com/", nil)
+		req.RemoteAddr = test.remoteAddr
+		for key, values := range test.headers {
+			for _, value := range values {
+				req.Header.Add(key, value)
+			}
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		if ip != test.ip || scheme != test.scheme || host != test.host {
+			t.Errorf("%s: expected %s %s %s but got %s %s %s", test.name, test.ip, test.scheme, test.host, ip, scheme, host)
+		}
+	}
+}
+
+func TestClientIPWithoutMiddleware(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "198.51.100.1:1234"
+	req.Header.Set("X-Forwarded-For", "192.0.2.1")
+
+	if ClientIP(req) != "198.51.100.1" || RequestScheme(req) != "http" {
+		t.Errorf("Expected the peer address without the middleware but got %s %s", ClientIP(req), RequestScheme(req))
+	}
+}
+
+func TestRealIPPropagation(t *testing.T) {
+	var logs bytes.Buffer
+	r := NewRouter()
+	r.Use(AccessLogMiddleware(AccessLogOptions{Logger: slog.New(slog.NewTextHandler(&logs, nil))}))
+	r.Use(RealIPMiddleware("10.0.0.0/8"))
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) {})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.0.0.1:4000"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	if ClientIP(req) != "10.0.0.1" {
+		t.Errorf("Expected the caller's request to be left unchanged but got %s", ClientIP(req))
+	}
+	if !strings.Contains(logs.String(), "remote_ip=198.51.100.1") {
+		t.Errorf("Expected the access log to see the resolved address but got %s", logs.String())
+	}
+}
@@ -0,0 +1,81 @@
+package httprouter
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+type RequestIDOptions struct {
+	// Header is read from the request and echoed on the response, defaults to X-Request-ID
+	Header string
+
+	// MaxLength is the longest incoming id that is accepted, defaults to 64
+	MaxLength int
+
+	// Generator creates ids for requests without a valid one, defaults to 16 random bytes as hex
+	Generator func() string
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func validRequestID(id string, maxLength int) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		isAlnum := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+		if !isAlnum && c != '-' && c != '_' && c != '.' && c != ':' && c != '+' && c != '/' && c != '=' {
+			return false
+		}
+	}
+	return true
+}
+
+func RequestIDMiddleware(opts RequestIDOptions) Middleware {
+	if opts.Header == "" {
+		opts.Header = "X-Request-ID"
+	}
+	if opts.MaxLength <= 0 {
+		opts.MaxLength = 64
+	}
+	if opts.Generator == nil {
+		opts.Generator = newRequestID
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(opts.Header)
+			if !validRequestID(id, opts.MaxLength) {
+				id = opts.Generator()
+			}
+			w.Header().Set(opts.Header, id)
+
+			if state := stateOf(r); state != nil {
+				state.mu.Lock()
+				state.requestID = id
+				state.mu.Unlock()
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey, id)))
+		})
+	}
+}
+
+// RequestID is the id set by RequestIDMiddleware, middlewares added to the router before it can read it once it has run
+func RequestID(r *http.Request) string {
+	if id, ok := r.Context().Value(requestIDKey).(string); ok {
+		return id
+	}
+	if state := stateOf(r); state != nil {
+		state.mu.Lock()
+		defer state.mu.Unlock()
+		return state.requestID
+	}
+	return ""
+}
@@ -1,8 +1,11 @@
 package httprouter
 
 import (
+	"context"
 	"net/http"
+	"net/url"
 	"strings"
+	"time"
 )
 
 type Router interface {
@@ -25,16 +28,84 @@ type Router interface {
 	Delete(route string, routeHandler http.HandlerFunc)
 }
 
+// implemented by the routers of this package, it mounts a handler along with the middlewares and metadata gathered by
+// the builders and sub routers it went through
+type routeMounter interface {
+	mount(prefix string, handler http.Handler, middlewares []Middleware, meta routeMeta)
+}
+
+// implemented by the routers of this package, it registers a route along with the middlewares and metadata gathered by
+// the builders and sub routers it went through without making them part of Router
+type routeRegistrar interface {
+	handle(method string, route string, routeHandler http.HandlerFunc, middlewares []Middleware, meta routeMeta)
+}
+
 type ServerRouter struct {
 	prefix          string
 	middlewares     []Middleware
-	trie            Trie[http.Handler]
+	trie            Trie[routeEntry]
+	mounts          Trie[routeEntry]
 	notFoundHandler http.HandlerFunc
+	errorHandler    ErrorHandlerFunc
+	errorRenderer   ErrorRenderer
+	hooks           routerHooks
+}
+
+type routeEntry struct {
+	method  string
+	pattern string
+	handler http.Handler
+	meta    routeMeta
+
+	// the route's middlewares around a handler answering OPTIONS requests, used for preflight requests
+	preflight http.Handler
+}
+
+type routeMeta struct {
+	name        string
+	prefix      string
+	permissions []string
+	values      map[string]any
+
+	// the values of the route markers among the route's middlewares, from the outermost to the innermost
+	markers map[string][]any
+}
+
+// routeMarker is returned by middlewares that configure the route they are added to, its value is recorded in the
+// route's metadata when the route is registered so that the middleware can find it once the route has been matched
+type routeMarker struct {
+	http.Handler
+	key   string
+	value any
 }
 
-func notFound(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusNotFound)
-	w.Write([]byte("404 not found"))
+// merges the inner metadata into a copy of the outer metadata, inner values take precedence
+func (meta routeMeta) merge(inner routeMeta) routeMeta {
+	merged := routeMeta{
+		name:        meta.name,
+		prefix:      meta.prefix + inner.prefix,
+		permissions: append(append([]string{}, meta.permissions...), inner.permissions...),
+		values:      make(map[string]any, len(meta.values)+len(inner.values)),
+	}
+	for k, v := range meta.values {
+		merged.values[k] = v
+	}
+	for k, v := range inner.values {
+		merged.values[k] = v
+	}
+	if inner.name != "" {
+		merged.name = inner.name
+	}
+	return merged
+}
+
+// RouteInfo describes a registered route and the metadata attached to it
+type RouteInfo struct {
+	Method      string
+	Pattern     string
+	Name        string
+	Permissions []string
+	Meta        map[string]any
 }
 
 func buildHandler(baseHandler http.HandlerFunc, middlewares ...Middleware) http.Handler {
@@ -46,11 +117,37 @@ func buildHandler(baseHandler http.HandlerFunc, middlewares ...Middleware) http.
 	return middleware(nextMiddleware)
 }
 
+// builds the handler of a route and records the values of the route markers in its metadata from the outermost to the innermost
+func buildRouteHandler(baseHandler http.HandlerFunc, meta *routeMeta, middlewares ...Middleware) http.Handler {
+	meta.markers = make(map[string][]any)
+	handler := http.Handler(baseHandler)
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+		if marker, ok := handler.(*routeMarker); ok {
+			meta.markers[marker.key] = append([]any{marker.value}, meta.markers[marker.key]...)
+			handler = marker.Handler
+		}
+	}
+	return handler
+}
+
+// reports whether the marker value was recorded for the route along with a marker of the same key inside it
+func (meta routeMeta) overridden(key string, value any) bool {
+	values := meta.markers[key]
+	for i, v := range values {
+		if v == value {
+			return i < len(values)-1
+		}
+	}
+	return false
+}
+
 func NewRouter() *ServerRouter {
 	return &ServerRouter{
-		middlewares:     []Middleware{},
-		trie:            newTrie[http.Handler](),
-		notFoundHandler: notFound,
+		middlewares:   []Middleware{},
+		trie:          newTrie[routeEntry](),
+		mounts:        newTrie[routeEntry](),
+		errorRenderer: TextErrorRenderer,
 	}
 }
 
@@ -62,15 +159,70 @@ func (router *ServerRouter) NotFound(routeHandler http.HandlerFunc) {
 	router.notFoundHandler = routeHandler
 }
 
+func (router *ServerRouter) ErrorHandler(handler ErrorHandlerFunc) {
+	router.errorHandler = handler
+}
+
+func (router *ServerRouter) ErrorRenderer(renderer ErrorRenderer) {
+	router.errorRenderer = renderer
+}
+
+func (router *ServerRouter) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	router.hooks.error(r, err)
+	router.renderError(w, r, err)
+}
+
+// responds with the error without running the error hooks, for requests the not found hooks already reported
+func (router *ServerRouter) renderError(w http.ResponseWriter, r *http.Request, err error) {
+	if router.errorHandler != nil {
+		router.errorHandler(w, r, err)
+	} else {
+		router.errorRenderer(w, r, toHTTPError(err))
+	}
+}
+
 func (router *ServerRouter) With(m Middleware) RouteBuilder {
 	return RouteBuilder{
-		middleware: m,
-		router:     router,
+		middlewares: []Middleware{m},
+		router:      router,
 	}
 }
 
+func (router *ServerRouter) Require(permissions ...string) RouteBuilder {
+	return RouteBuilder{router: router}.Require(permissions...)
+}
+
+func (router *ServerRouter) Meta(key string, value any) RouteBuilder {
+	return RouteBuilder{router: router}.Meta(key, value)
+}
+
+func (router *ServerRouter) Name(name string) RouteBuilder {
+	return RouteBuilder{router: router}.Name(name)
+}
+
 func (router *ServerRouter) Routes() []string {
-	return router.trie.routes()
+	return append(router.trie.routes(), router.mounts.routes()...)
+}
+
+// Walk calls fn for each registered route in the order the methods were registered, then for each mounted handler
+// with the method * and its prefix as the pattern, stopping at the first error
+func (router *ServerRouter) Walk(fn func(route RouteInfo) error) error {
+	visit := func(rt *routeEntry) error {
+		if rt.handler == nil {
+			return nil
+		}
+		return fn(RouteInfo{
+			Method:      rt.method,
+			Pattern:     rt.pattern,
+			Name:        rt.meta.name,
+			Permissions: append([]string{}, rt.meta.permissions...),
+			Meta:        rt.meta.merge(routeMeta{}).values,
+		})
+	}
+	if err := router.trie.walk(visit); err != nil {
+		return err
+	}
+	return router.mounts.walk(visit)
 }
 
 func (router *ServerRouter) Prefix(p string) SubRouterBuilder {
@@ -98,24 +250,188 @@ func (router *ServerRouter) Delete(route string, routeHandler http.HandlerFunc)
 }
 
 func (router *ServerRouter) Route(method string, route string, routeHandler http.HandlerFunc) {
+	router.handle(method, route, routeHandler, nil, routeMeta{})
+}
+
+func (router *ServerRouter) handle(method string, route string, routeHandler http.HandlerFunc, middlewares []Middleware, meta routeMeta) {
 	route = router.prefix + route
-	handler := buildHandler(routeHandler, router.middlewares...)
-	router.trie.insert(method, route, handler)
+	meta.prefix = router.prefix + meta.prefix
+	middlewares = append(append([]Middleware{}, router.middlewares...), middlewares...)
+	handler := buildRouteHandler(routeHandler, &meta, middlewares...)
+	router.trie.insert(method, route, routeEntry{
+		method:    method,
+		pattern:   route,
+		handler:   handler,
+		meta:      meta,
+		preflight: buildHandler(optionsHandler, middlewares...),
+	})
+}
+
+// mounted handlers are stored under a single method since they serve every method
+const mountMethod = "*"
+
+// Mount delegates the requests under prefix that no route matches to handler, which sees the path with the prefix
+// removed. The deepest mount whose prefix ends on a segment boundary of the path is used, and mounted handlers are
+// wrapped in the router's middlewares like routes. Permissions are required for a mount with r.Require(...).Mount(...),
+// and CurrentRoute has the method * for requests served by a mount
+func (router *ServerRouter) Mount(prefix string, handler http.Handler) {
+	router.mount(prefix, handler, nil, routeMeta{})
+}
+
+func (router *ServerRouter) mount(prefix string, handler http.Handler, middlewares []Middleware, meta routeMeta) {
+	prefix = router.prefix + prefix
+	// the prefix of a mount covers the whole subtree it serves
+	meta.prefix = prefix
+	middlewares = append(append([]Middleware{}, router.middlewares...), middlewares...)
+	h := buildRouteHandler(handler.ServeHTTP, &meta, middlewares...)
+	router.mounts.insert(mountMethod, prefix, routeEntry{method: mountMethod, pattern: prefix, handler: h, meta: meta})
+}
+
+func (router *ServerRouter) RouteE(method string, route string, routeHandler HandlerFuncE) {
+	router.Route(method, route, HandleE(routeHandler))
 }
 
 func (router *ServerRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	path := strings.Trim(r.RequestURI, " \n\t")
+	if len(router.hooks.onComplete) == 0 {
+		router.serve(w, r)
+		return
+	}
 
-	handler, err := router.trie.find(r.Method, path)
+	start := time.Now()
+	ww := WrapResponseWriter(w)
+	match := router.serve(ww, r)
+	router.hooks.complete(r, CompletedRequest{
+		Route:        match,
+		Status:       ww.Status(),
+		BytesWritten: ww.BytesWritten(),
+		Duration:     time.Since(start),
+	})
+}
+
+// serves the request and returns the route that matched it, if any
+func (router *ServerRouter) serve(w http.ResponseWriter, r *http.Request) *RouteMatch {
+	// the query string isn't part of the route
+	path, _, _ := strings.Cut(strings.Trim(r.RequestURI, " \n\t"), "?")
+
+	ctx := context.WithValue(r.Context(), errorHandlerKey, ErrorHandlerFunc(router.handleError))
+	if stateOf(r) == nil {
+		ctx = context.WithValue(ctx, requestStateKey, &requestState{})
+	}
+	r = r.WithContext(ctx)
+
+	rt, err := router.trie.find(r.Method, path)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(err.Error()))
-		return
+		router.handleError(w, r, err)
+		return nil
+	}
+
+	if rt == nil || rt.handler == nil {
+		return router.serveNoMatch(w, r, path)
+	}
+
+	match := &RouteMatch{
+		Pattern: rt.pattern,
+		Method:  rt.method,
+		Prefix:  rt.meta.prefix,
+		Name:    rt.meta.name,
+		entry:   rt,
+	}
+	r = r.WithContext(context.WithValue(r.Context(), routeKey, match))
+	router.hooks.match(r, match)
+	rt.handler.ServeHTTP(w, r)
+	return match
+}
+
+// answers requests that no route matches, returning the mount that served it if any
+func (router *ServerRouter) serveNoMatch(w http.ResponseWriter, r *http.Request, path string) *RouteMatch {
+	allowed, err := router.allowedMethods(path)
+	if err != nil {
+		router.handleError(w, r, err)
+		return nil
 	}
 
-	if handler == nil || *handler == nil {
+	if len(allowed) == 0 {
+		if mount, rest, err := router.mounts.findPrefix(mountMethod, path); err == nil && mount != nil {
+			return router.serveMount(w, r, mount, rest)
+		}
+	}
+
+	if len(allowed) > 0 {
+		w.Header().Set("Allow", strings.Join(append(allowed, "OPTIONS"), ", "))
+		if r.Method == "OPTIONS" {
+			// answered through the middlewares of a route on the path so they can respond to preflight requests,
+			// even when they were added to a sub router or with With
+			router.preflightHandler(path, allowed, r.Header.Get("Access-Control-Request-Method")).ServeHTTP(w, r)
+		} else {
+			router.hooks.notFound(r, http.StatusMethodNotAllowed)
+			router.renderError(w, r, ErrMethodNotAllowed)
+		}
+	} else if router.notFoundHandler != nil {
+		router.hooks.notFound(r, http.StatusNotFound)
 		router.notFoundHandler(w, r)
 	} else {
-		(*handler).ServeHTTP(w, r)
+		router.hooks.notFound(r, http.StatusNotFound)
+		router.renderError(w, r, ErrRouteNotFound)
+	}
+	return nil
+}
+
+// serves the request with the mounted handler, removing the mount's prefix from the request like http.StripPrefix
+func (router *ServerRouter) serveMount(w http.ResponseWriter, r *http.Request, mount *routeEntry, rest string) *RouteMatch {
+	match := &RouteMatch{
+		Pattern: mount.pattern,
+		Method:  mountMethod,
+		Prefix:  mount.meta.prefix,
+		entry:   mount,
+	}
+	mr := r.WithContext(context.WithValue(r.Context(), routeKey, match))
+	mr.URL = new(url.URL)
+	*mr.URL = *r.URL
+	mr.URL.Path = withLeadingSlash(strings.TrimPrefix(r.URL.Path, mount.pattern))
+	if r.URL.RawPath != "" {
+		mr.URL.RawPath = withLeadingSlash(strings.TrimPrefix(r.URL.RawPath, mount.pattern))
+	}
+	mr.RequestURI = withLeadingSlash(rest)
+	if r.URL.RawQuery != "" {
+		mr.RequestURI += "?" + r.URL.RawQuery
+	}
+
+	router.hooks.match(mr, match)
+	mount.handler.ServeHTTP(w, mr)
+	return match
+}
+
+func withLeadingSlash(path string) string {
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return path
+}
+
+// the route of the method a preflight request asks for, or else of the first allowed method
+func (router *ServerRouter) preflightHandler(path string, allowed []string, requested string) http.Handler {
+	method := allowed[0]
+	if contains(allowed, requested) {
+		method = requested
+	}
+	rt, _ := router.trie.find(method, path)
+	return rt.preflight
+}
+
+func optionsHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (router *ServerRouter) allowedMethods(path string) ([]string, error) {
+	allowed := make([]string, 0)
+	for _, method := range router.trie.methods {
+		rt, err := router.trie.find(method, path)
+		if err != nil {
+			return nil, err
+		}
+		if rt != nil && rt.handler != nil && method != "OPTIONS" {
+			allowed = append(allowed, method)
+		}
 	}
+	return allowed, nil
 }
@@ -2,6 +2,7 @@ package httprouter
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"log"
 	"math/rand"
@@ -19,6 +20,7 @@ func createTestRouter() *ServerRouter {
 
 	r.Use(LoggerMiddleware(log.Default()))
 	r.Use(CorsMiddleware())
+	r.Use(BodyLimitMiddleware(1 << 20))
 
 	r.Use(func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -127,6 +129,7 @@ func TestRouter(t *testing.T) {
 		{method: "POST", url: "/api/echo", bodyIn: "Stop", bodyOut: "1 2 Early Stop 1"},
 		{method: "GET", url: "/api/products/ping", bodyIn: "", bodyOut: "1 2 Pong! 2 1"},
 		{method: "GET", url: "/api/products/ping/pong", bodyIn: "", bodyOut: "1 2 Ping Pong! 2 1"},
+		{method: "GET", url: "/api/products/ping?name=pong", bodyIn: "", bodyOut: "1 2 Pong! 2 1"},
 	}
 
 	fail := false
@@ -231,3 +234,272 @@ func BenchmarkSendRoutes(b *testing.B) {
 		b.Fatalf("One or more route tests failed. Read logs.")
 	}
 }
+
+func TestRouterErrors(t *testing.T) {
+	r := NewRouter()
+	r.Get("/products", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Products"))
+	})
+	r.Put("/products", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Products"))
+	})
+
+	type Test struct {
+		method  string
+		url     string
+		status  int
+		allow   string
+		bodyOut string
+	}
+
+	testTable := []Test{
+		{method: "GET", url: "/products", status: http.StatusOK, bodyOut: "Products"},
+		{method: "GET", url: "/articles", status: http.StatusNotFound, bodyOut: "404 not found"},
+		{method: "POST", url: "/products", status: http.StatusMethodNotAllowed, allow: "GET, PUT, OPTIONS", bodyOut: "405 method not allowed"},
+		{method: "OPTIONS", url: "/products", status: http.StatusNoContent, allow: "GET, PUT, OPTIONS", bodyOut: ""},
+	}
+
+	for i, test := range testTable {
+		req := httptest.NewRequest(test.method, test.url, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != test.status || w.Body.String() != test.bodyOut || w.Header().Get("Allow") != test.allow {
+			t.Errorf("Failed test %d, expected %d %q with Allow %q, got %d %q with Allow %q",
+				i, test.status, test.bodyOut, test.allow, w.Code, w.Body.String(), w.Header().Get("Allow"))
+		}
+	}
+
+	var handled error
+	r.ErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+		handled = err
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("DELETE", "/products", nil))
+	if !errors.Is(handled, ErrMethodNotAllowed) || w.Code != http.StatusTeapot {
+		t.Errorf("Expected custom error handler to receive ErrMethodNotAllowed but got %v", handled)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/articles", nil))
+	if !errors.Is(handled, ErrRouteNotFound) {
+		t.Errorf("Expected custom error handler to receive ErrRouteNotFound but got %v", handled)
+	}
+}
+
+func TestPatternError(t *testing.T) {
+	trie := newTrie[int]()
+
+	_, err := trie.getRegex("[a-z")
+
+	var patternErr *PatternError
+	if !errors.Is(err, ErrBadPattern) || !errors.As(err, &patternErr) || patternErr.Pattern != "[a-z" {
+		t.Errorf("Expected a PatternError for an invalid regex but got %v", err)
+	}
+}
+
+func TestRouterErrorRenderers(t *testing.T) {
+	r := NewRouter()
+	r.RouteE("GET", "/stock", func(w http.ResponseWriter, r *http.Request) error {
+		return &HTTPError{Status: http.StatusConflict, Code: "out_of_stock", Message: "product is out of stock"}
+	})
+	r.RouteE("GET", "/db", func(w http.ResponseWriter, r *http.Request) error {
+		return errors.New("connection refused to 10.0.0.1")
+	})
+	r.With(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			HandleError(w, r, NewHTTPError(http.StatusUnauthorized, "unauthorized", "missing token"))
+		})
+	}).Get("/private", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Private"))
+	})
+
+	type Test struct {
+		renderer    ErrorRenderer
+		url         string
+		status      int
+		contentType string
+		bodyOut     string
+	}
+
+	testTable := []Test{
+		{renderer: TextErrorRenderer, url: "/stock", status: http.StatusConflict,
+			contentType: "text/plain; charset=utf-8", bodyOut: "409 product is out of stock"},
+		{renderer: TextErrorRenderer, url: "/db", status: http.StatusInternalServerError,
+			contentType: "text/plain; charset=utf-8", bodyOut: "500 internal server error"},
+		{renderer: JSONErrorRenderer, url: "/stock", status: http.StatusConflict, contentType: "application/json",
+			bodyOut: `{"status":409,"code":"out_of_stock","message":"product is out of stock"}`},
+		{renderer: JSONErrorRenderer, url: "/private", status: http.StatusUnauthorized, contentType: "application/json",
+			bodyOut: `{"status":401,"code":"unauthorized","message":"missing token"}`},
+		{renderer: ProblemErrorRenderer, url: "/db", status: http.StatusInternalServerError, contentType: "application/problem+json",
+			bodyOut: `{"type":"about:blank","title":"Internal Server Error","status":500,"instance":"/db"}`},
+		{renderer: ProblemErrorRenderer, url: "/missing", status: http.StatusNotFound, contentType: "application/problem+json",
+			bodyOut: `{"type":"about:blank","title":"Not Found","status":404,"instance":"/missing"}`},
+	}
+
+	for i, test := range testTable {
+		r.ErrorRenderer(test.renderer)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", test.url, nil))
+
+		if w.Code != test.status || w.Header().Get("Content-Type") != test.contentType || w.Body.String() != test.bodyOut {
+			t.Errorf("Failed test %d, expected %d %s %q, got %d %s %q", i, test.status, test.contentType, test.bodyOut,
+				w.Code, w.Header().Get("Content-Type"), w.Body.String())
+		}
+	}
+}
+
+func TestCurrentRoute(t *testing.T) {
+	r := Prefix("/api").NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Route", CurrentRoute(r).Pattern)
+			next.ServeHTTP(w, r)
+		})
+	})
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		if CurrentRoute(r) != nil {
+			t.Errorf("Expected no route for an unmatched request")
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	writeRoute := func(w http.ResponseWriter, r *http.Request) {
+		match := CurrentRoute(r)
+		w.Write([]byte(match.Method + " " + match.Pattern + " " + match.Prefix + " " + match.Name))
+	}
+	r.Get("/health", writeRoute)
+	v1 := r.Prefix("/v1").SubRouter()
+	v1.(*SubRouter).Name("create-order").Post("/orders", writeRoute)
+	admin := v1.Prefix("/admin").SubRouter()
+	admin.With(BodyLimitMiddleware(1024)).Name("admin-users").Get("/users", writeRoute)
+
+	type Test struct {
+		method  string
+		url     string
+		bodyOut string
+	}
+
+	testTable := []Test{
+		{method: "GET", url: "/api/health?verbose=1", bodyOut: "GET /api/health /api "},
+		{method: "POST", url: "/api/v1/orders", bodyOut: "POST /api/v1/orders /api/v1 create-order"},
+		{method: "GET", url: "/api/v1/admin/users", bodyOut: "GET /api/v1/admin/users /api/v1/admin admin-users"},
+	}
+
+	for i, test := range testTable {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(test.method, test.url, nil))
+
+		if w.Body.String() != test.bodyOut {
+			t.Errorf("Failed test %d, expected %q but got %q", i, test.bodyOut, w.Body.String())
+		}
+		if route := w.Header().Get("X-Route"); !strings.HasPrefix(test.bodyOut, test.method+" "+route+" ") {
+			t.Errorf("Failed test %d, expected the middleware to see the route but got %q", i, route)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected a 404 but got %d", w.Code)
+	}
+}
+
+// a Router implemented outside the package, which can only wrap the package's routers
+type recordingRouter struct {
+	Router
+	routes []string
+}
+
+func (router *recordingRouter) Route(method string, route string, routeHandler http.HandlerFunc) {
+	router.routes = append(router.routes, method+" "+route)
+	router.Router.Route(method, route, routeHandler)
+}
+
+func TestExternalRouter(t *testing.T) {
+	inner := NewRouter()
+	r := &recordingRouter{Router: inner}
+
+	sr := SubRouterBuilder{parent: r, prefix: "/api"}.Meta("owner", "platform").SubRouter()
+	sr.With(BodyLimitMiddleware(1024)).Name("users").Get("/users", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("users"))
+	})
+
+	if len(r.routes) != 1 || r.routes[0] != "GET /api/users" {
+		t.Errorf("Expected the route to be registered through the external router but got %v", r.routes)
+	}
+
+	w := httptest.NewRecorder()
+	inner.ServeHTTP(w, httptest.NewRequest("GET", "/api/users", nil))
+	if w.Body.String() != "users" {
+		t.Errorf("Expected users but got %q", w.Body.String())
+	}
+
+	// the permissions can't reach AuthorizeMiddleware so the route would be left unprotected
+	defer func() {
+		if rec := recover(); rec == nil {
+			t.Errorf("Expected requiring permissions through an external router to panic")
+		}
+	}()
+	admin := SubRouterBuilder{parent: r, prefix: "/admin"}.Require("admin").SubRouter()
+	admin.Get("/users", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("secret"))
+	})
+}
+
+func TestMount(t *testing.T) {
+	r := NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Middleware", "true")
+			next.ServeHTTP(w, r)
+		})
+	})
+	writePath := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path + " " + r.RequestURI + " " + routePattern(r)))
+	}
+	r.Get("/static/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("robots"))
+	})
+	r.Mount("/static", http.HandlerFunc(writePath))
+	r.Mount("/api", http.HandlerFunc(writePath))
+
+	v2 := NewRouter()
+	v2.Get("/users", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("users"))
+	})
+	r.Mount("/api/v2", v2)
+
+	type Test struct {
+		method  string
+		url     string
+		status  int
+		bodyOut string
+	}
+
+	testTable := []Test{
+		{method: "GET", url: "/static/css/site.css?v=1", status: http.StatusOK, bodyOut: "/css/site.css /css/site.css?v=1 /static"},
+		{method: "POST", url: "/static", status: http.StatusOK, bodyOut: "/ / /static"},
+		{method: "GET", url: "/static/robots.txt", status: http.StatusOK, bodyOut: "robots"},
+		{method: "POST", url: "/static/robots.txt", status: http.StatusMethodNotAllowed, bodyOut: "405 method not allowed"},
+		{method: "GET", url: "/staticfiles", status: http.StatusNotFound, bodyOut: "404 not found"},
+		{method: "GET", url: "/api/v1/users", status: http.StatusOK, bodyOut: "/v1/users /v1/users /api"},
+		{method: "GET", url: "/api/v2/users", status: http.StatusOK, bodyOut: "users"},
+		{method: "GET", url: "/api/v2/orders", status: http.StatusNotFound, bodyOut: "404 not found"},
+	}
+
+	for i, test := range testTable {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(test.method, test.url, nil))
+
+		if w.Code != test.status || w.Body.String() != test.bodyOut {
+			t.Errorf("Failed test %d, expected %d %q but got %d %q", i, test.status, test.bodyOut, w.Code, w.Body.String())
+		}
+		if test.status == http.StatusOK && w.Header().Get("X-Middleware") != "true" {
+			t.Errorf("Failed test %d, expected the router's middlewares to run", i)
+		}
+	}
+}
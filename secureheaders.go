@@ -0,0 +1,111 @@
+package httprouter
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type SecureHeadersOptions struct {
+	// HSTSMaxAge is in seconds, Strict-Transport-Security is only sent over TLS and is omitted when 0
+	HSTSMaxAge            int
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+
+	// TrustForwardedProto treats requests with X-Forwarded-Proto https as TLS, only enable it behind a trusted proxy.
+	// The scheme resolved by RealIPMiddleware is always used
+	TrustForwardedProto bool
+
+	ContentTypeNosniff bool
+	FrameOptions       string
+	ReferrerPolicy     string
+	PermissionsPolicy  string
+
+	// ContentSecurityPolicy may contain {nonce} which is replaced with a new nonce for each request
+	ContentSecurityPolicy string
+	CSPReportOnly         bool
+}
+
+func StrictSecureHeaders() SecureHeadersOptions {
+	return SecureHeadersOptions{
+		HSTSMaxAge:            63072000,
+		HSTSIncludeSubdomains: true,
+		ContentTypeNosniff:    true,
+		FrameOptions:          "DENY",
+		ReferrerPolicy:        "no-referrer",
+		PermissionsPolicy:     "camera=(), microphone=(), geolocation=(), payment=(), usb=()",
+		ContentSecurityPolicy: "default-src 'self'; script-src 'self' 'nonce-{nonce}'; style-src 'self' 'nonce-{nonce}'; " +
+			"object-src 'none'; base-uri 'self'; frame-ancestors 'none'",
+	}
+}
+
+func newCSPNonce() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+func SecureHeadersMiddleware(opts SecureHeadersOptions) Middleware {
+	hsts := ""
+	if opts.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.Itoa(opts.HSTSMaxAge)
+		if opts.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if opts.HSTSPreload {
+			hsts += "; preload"
+		}
+	}
+	cspHeader := "Content-Security-Policy"
+	if opts.CSPReportOnly {
+		cspHeader = "Content-Security-Policy-Report-Only"
+	}
+	usesNonce := strings.Contains(opts.ContentSecurityPolicy, "{nonce}")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := w.Header()
+			if hsts != "" && isSecureRequest(r, opts.TrustForwardedProto) {
+				header.Set("Strict-Transport-Security", hsts)
+			}
+			if opts.ContentTypeNosniff {
+				header.Set("X-Content-Type-Options", "nosniff")
+			}
+			if opts.FrameOptions != "" {
+				header.Set("X-Frame-Options", opts.FrameOptions)
+			}
+			if opts.ReferrerPolicy != "" {
+				header.Set("Referrer-Policy", opts.ReferrerPolicy)
+			}
+			if opts.PermissionsPolicy != "" {
+				header.Set("Permissions-Policy", opts.PermissionsPolicy)
+			}
+			if opts.ContentSecurityPolicy != "" {
+				csp := opts.ContentSecurityPolicy
+				if usesNonce {
+					nonce := newCSPNonce()
+					csp = strings.ReplaceAll(csp, "{nonce}", nonce)
+					r = r.WithContext(context.WithValue(r.Context(), cspNonceKey, nonce))
+				}
+				header.Set(cspHeader, csp)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func isSecureRequest(r *http.Request, trustForwardedProto bool) bool {
+	if RequestScheme(r) == "https" {
+		return true
+	}
+	return trustForwardedProto && strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
+
+// CSPNonce is the nonce of the request's Content-Security-Policy for use in script and style tags of templates
+func CSPNonce(r *http.Request) string {
+	nonce, _ := r.Context().Value(cspNonceKey).(string)
+	return nonce
+}
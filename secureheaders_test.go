@@ -0,0 +1,95 @@
+package httprouter
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSecureHeadersMiddleware(t *testing.T) {
+	reportOnly := StrictSecureHeaders()
+	reportOnly.CSPReportOnly = true
+	reportOnly.TrustForwardedProto = true
+
+	type Test struct {
+		opts     SecureHeadersOptions
+		tls      bool
+		proto    string
+		expected map[string]string
+	}
+
+	strictHeaders := func(hsts string) map[string]string {
+		return map[string]string{
+			"Strict-Transport-Security": hsts,
+			"X-Content-Type-Options":    "nosniff",
+			"X-Frame-Options":           "DENY",
+			"Referrer-Policy":           "no-referrer",
+			"Permissions-Policy":        "camera=(), microphone=(), geolocation=(), payment=(), usb=()",
+		}
+	}
+
+	testTable := []Test{
+		{opts: StrictSecureHeaders(), tls: true, expected: strictHeaders("max-age=63072000; includeSubDomains")},
+		{opts: StrictSecureHeaders(), expected: strictHeaders("")},
+		{opts: StrictSecureHeaders(), proto: "https", expected: strictHeaders("")},
+		{opts: reportOnly, proto: "https", expected: strictHeaders("max-age=63072000; includeSubDomains")},
+		{opts: SecureHeadersOptions{HSTSMaxAge: 60, HSTSPreload: true, ReferrerPolicy: "same-origin"}, tls: true,
+			expected: map[string]string{"Strict-Transport-Security": "max-age=60; preload", "Referrer-Policy": "same-origin"}},
+	}
+
+	for i, test := range testTable {
+		var nonce string
+		handler := SecureHeadersMiddleware(test.opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			nonce = CSPNonce(r)
+		}))
+
+		req := httptest.NewRequest("GET", "/", nil)
+		if test.tls {
+			req.TLS = &tls.ConnectionState{}
+		}
+		if test.proto != "" {
+			req.Header.Set("X-Forwarded-Proto", test.proto)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		for _, key := range []string{"Strict-Transport-Security", "X-Content-Type-Options", "X-Frame-Options", "Referrer-Policy", "Permissions-Policy"} {
+			if w.Header().Get(key) != test.expected[key] {
+				t.Errorf("Failed test %d, expected %s to be %q but got %q", i, key, test.expected[key], w.Header().Get(key))
+			}
+		}
+
+		cspHeader, otherHeader := "Content-Security-Policy", "Content-Security-Policy-Report-Only"
+		if test.opts.CSPReportOnly {
+			cspHeader, otherHeader = otherHeader, cspHeader
+		}
+		csp := w.Header().Get(cspHeader)
+		if test.opts.ContentSecurityPolicy == "" {
+			if csp != "" || nonce != "" {
+				t.Errorf("Failed test %d, expected no policy or nonce but got %q %q", i, csp, nonce)
+			}
+			continue
+		}
+		if nonce == "" || !strings.Contains(csp, "script-src 'self' 'nonce-"+nonce+"'") || strings.Contains(csp, "{nonce}") {
+			t.Errorf("Failed test %d, expected the policy to contain the request nonce %q but got %q", i, nonce, csp)
+		}
+		if w.Header().Get(otherHeader) != "" {
+			t.Errorf("Failed test %d, expected %s to not be set", i, otherHeader)
+		}
+	}
+}
+
+func TestCSPNonceUnique(t *testing.T) {
+	nonces := make(map[string]bool)
+	handler := SecureHeadersMiddleware(StrictSecureHeaders())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nonces[CSPNonce(r)] = true
+	}))
+	for i := 0; i < 10; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}
+	if len(nonces) != 10 {
+		t.Errorf("Expected a new nonce for each request but got %d unique nonces", len(nonces))
+	}
+}
@@ -0,0 +1,458 @@
+package httprouter
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SessionRecord is the persisted state of a session
+type SessionRecord struct {
+	Values   map[string]string `json:"v,omitempty"`
+	Flashes  []string          `json:"f,omitempty"`
+	Created  time.Time         `json:"c"`
+	LastSeen time.Time         `json:"s"`
+}
+
+// SessionStore persists sessions, the cookie holds whatever value the store returns from Save
+type SessionStore interface {
+	// Load returns the session the cookie value refers to, ok is false if it doesn't exist or can't be read
+	Load(value string) (record SessionRecord, ok bool)
+
+	// Save persists the session previously stored under value, which is empty for new sessions, and returns the new cookie value.
+	// The session should be kept for at least ttl
+	Save(value string, record SessionRecord, ttl time.Duration) (string, error)
+
+	Delete(value string) error
+}
+
+var errSessionTooLarge = errors.New("session is too large to store in a cookie")
+
+// CookieStore stores sessions in the cookie itself, encrypted with AES-GCM and authenticated with HMAC-SHA256
+type CookieStore struct {
+	keys []cookieKey
+}
+
+type cookieKey struct {
+	aead cipher.AEAD
+	mac  []byte
+}
+
+// browsers limit cookies to about 4096 bytes including the name and attributes
+const maxCookieValue = 3800
+
+func deriveKey(secret []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+// NewCookieStore creates a store from secrets of at least 32 bytes. Sessions are sealed with the first secret
+// and opened with any of them, so secrets are rotated by adding the new one to the front
+func NewCookieStore(secrets ...[]byte) *CookieStore {
+	if len(secrets) == 0 {
+		panic("httprouter: cookie store requires at least one secret")
+	}
+	store := &CookieStore{}
+	for _, secret := range secrets {
+		if len(secret) < 32 {
+			panic("httprouter: cookie store secrets must be at least 32 bytes")
+		}
+		block, err := aes.NewCipher(deriveKey(secret, "session encryption"))
+		if err != nil {
+			panic(err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			panic(err)
+		}
+		store.keys = append(store.keys, cookieKey{aead: aead, mac: deriveKey(secret, "session authentication")})
+	}
+	return store
+}
+
+func (store *CookieStore) Load(value string) (SessionRecord, bool) {
+	var record SessionRecord
+	sealed, tag, ok := strings.Cut(value, ".")
+	if !ok {
+		return record, false
+	}
+	payload, err1 := base64.RawURLEncoding.DecodeString(sealed)
+	sum, err2 := base64.RawURLEncoding.DecodeString(tag)
+	if err1 != nil || err2 != nil {
+		return record, false
+	}
+
+	for _, key := range store.keys {
+		mac := hmac.New(sha256.New, key.mac)
+		mac.Write(payload)
+		if !hmac.Equal(mac.Sum(nil), sum) || len(payload) < key.aead.NonceSize() {
+			continue
+		}
+		nonce, ciphertext := payload[:key.aead.NonceSize()], payload[key.aead.NonceSize():]
+		plaintext, err := key.aead.Open(nil, nonce, ciphertext, nil)
+		if err != nil {
+			continue
+		}
+		return record, json.Unmarshal(plaintext, &record) == nil
+	}
+	return record, false
+}
+
+func (store *CookieStore) Save(value string, record SessionRecord, ttl time.Duration) (string, error) {
+	plaintext, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+	key := store.keys[0]
+	nonce := make([]byte, key.aead.NonceSize())
+	rand.Read(nonce)
+	payload := key.aead.Seal(nonce, nonce, plaintext, nil)
+
+	mac := hmac.New(sha256.New, key.mac)
+	mac.Write(payload)
+	sealed := base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	if len(sealed) > maxCookieValue {
+		return "", errSessionTooLarge
+	}
+	return sealed, nil
+}
+
+// Delete does nothing since the session only exists in the cookie, which the middleware removes
+func (store *CookieStore) Delete(value string) error {
+	return nil
+}
+
+type memorySession struct {
+	record  SessionRecord
+	expires time.Time
+}
+
+const sessionSweepInterval = time.Minute
+
+// MemorySessionStore keeps sessions in memory on the server, the cookie only holds a random session id
+type MemorySessionStore struct {
+	mu        sync.Mutex
+	sessions  map[string]memorySession
+	lastSweep time.Time
+}
+
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: make(map[string]memorySession)}
+}
+
+func copyRecord(record SessionRecord) SessionRecord {
+	values := make(map[string]string, len(record.Values))
+	for k, v := range record.Values {
+		values[k] = v
+	}
+	record.Values = values
+	record.Flashes = append([]string{}, record.Flashes...)
+	return record
+}
+
+func (store *MemorySessionStore) Load(value string) (SessionRecord, bool) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	session, ok := store.sessions[value]
+	if !ok || time.Now().After(session.expires) {
+		return SessionRecord{}, false
+	}
+	return copyRecord(session.record), true
+}
+
+func (store *MemorySessionStore) Save(value string, record SessionRecord, ttl time.Duration) (string, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(store.lastSweep) > sessionSweepInterval {
+		for id, session := range store.sessions {
+			if now.After(session.expires) {
+				delete(store.sessions, id)
+			}
+		}
+		store.lastSweep = now
+	}
+
+	if _, ok := store.sessions[value]; !ok {
+		b := make([]byte, 32)
+		rand.Read(b)
+		value = base64.RawURLEncoding.EncodeToString(b)
+	}
+	store.sessions[value] = memorySession{record: copyRecord(record), expires: now.Add(ttl)}
+	return value, nil
+}
+
+func (store *MemorySessionStore) Delete(value string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	delete(store.sessions, value)
+	return nil
+}
+
+func (store *MemorySessionStore) Len() int {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	return len(store.sessions)
+}
+
+// SessionData is the session of a request, changes are saved before the response header is written
+type SessionData struct {
+	mu        sync.Mutex
+	record    SessionRecord
+	isNew     bool
+	modified  bool
+	renewed   bool
+	destroyed bool
+}
+
+// Session is the session loaded by SessionMiddleware, or nil if there is none
+func Session(r *http.Request) *SessionData {
+	session, _ := r.Context().Value(sessionKey).(*SessionData)
+	return session
+}
+
+func (session *SessionData) Get(key string) string {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	return session.record.Values[key]
+}
+
+func (session *SessionData) Set(key string, value string) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if session.record.Values == nil {
+		session.record.Values = make(map[string]string)
+	}
+	session.record.Values[key] = value
+	session.modified = true
+}
+
+func (session *SessionData) Delete(key string) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if _, ok := session.record.Values[key]; ok {
+		delete(session.record.Values, key)
+		session.modified = true
+	}
+}
+
+// AddFlash adds a message that is kept until it is read with Flashes, usually on the next request
+func (session *SessionData) AddFlash(message string) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	session.record.Flashes = append(session.record.Flashes, message)
+	session.modified = true
+}
+
+// Flashes returns the flash messages and removes them from the session
+func (session *SessionData) Flashes() []string {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	flashes := session.record.Flashes
+	if len(flashes) > 0 {
+		session.record.Flashes = nil
+		session.modified = true
+	}
+	return flashes
+}
+
+func (session *SessionData) IsNew() bool {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	return session.isNew
+}
+
+// Renew moves the session to a new id, it should be called when the privileges of the session change such as on login
+func (session *SessionData) Renew() {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	session.renewed = true
+	session.modified = true
+}
+
+// Destroy removes the session from the store and the client
+func (session *SessionData) Destroy() {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	session.record = SessionRecord{}
+	session.destroyed = true
+}
+
+type SessionOptions struct {
+	Store SessionStore
+
+	// Name is the cookie name and defaults to session
+	Name     string
+	Path     string
+	Domain   string
+	SameSite http.SameSite
+
+	// IdleTimeout expires sessions that haven't been used for a while, AbsoluteTimeout expires them regardless of use.
+	// They default to 30 minutes and 24 hours
+	IdleTimeout     time.Duration
+	AbsoluteTimeout time.Duration
+
+	Now func() time.Time
+}
+
+// commits the session before the header is sent so the cookie can be set
+type sessionWriter struct {
+	ResponseWriter
+	committed bool
+	commit    func()
+}
+
+func (sw *sessionWriter) before() {
+	if !sw.committed {
+		sw.committed = true
+		sw.commit()
+	}
+}
+
+func (sw *sessionWriter) WriteHeader(status int) {
+	if status >= 200 || status == http.StatusSwitchingProtocols {
+		sw.before()
+	}
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *sessionWriter) Write(b []byte) (int, error) {
+	sw.before()
+	return sw.ResponseWriter.Write(b)
+}
+
+type sessionFlusher sessionWriter
+
+func (f *sessionFlusher) Flush() {
+	sw := (*sessionWriter)(f)
+	sw.before()
+	sw.ResponseWriter.(http.Flusher).Flush()
+}
+
+type sessionReaderFrom sessionWriter
+
+func (rf *sessionReaderFrom) ReadFrom(src io.Reader) (int64, error) {
+	sw := (*sessionWriter)(rf)
+	sw.before()
+	return sw.ResponseWriter.(io.ReaderFrom).ReadFrom(src)
+}
+
+// SessionMiddleware loads the session of each request from the store, the session is available with Session(r).
+// The cookie is only sent when the session is modified, or to refresh the idle timeout once a tenth of it has passed.
+// If the store fails to save a session its changes are lost
+func SessionMiddleware(opts SessionOptions) Middleware {
+	if opts.Store == nil {
+		panic("httprouter: session middleware requires a store")
+	}
+	if opts.Name == "" {
+		opts.Name = "session"
+	}
+	if opts.Path == "" {
+		opts.Path = "/"
+	}
+	if opts.SameSite == 0 {
+		opts.SameSite = http.SameSiteLaxMode
+	}
+	if opts.IdleTimeout == 0 {
+		opts.IdleTimeout = 30 * time.Minute
+	}
+	if opts.AbsoluteTimeout == 0 {
+		opts.AbsoluteTimeout = 24 * time.Hour
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			now := opts.Now()
+
+			value := ""
+			expired := false
+			session := &SessionData{}
+			if cookie, err := r.Cookie(opts.Name); err == nil {
+				value = cookie.Value
+				record, ok := opts.Store.Load(value)
+				if ok && now.Sub(record.LastSeen) < opts.IdleTimeout && now.Sub(record.Created) < opts.AbsoluteTimeout {
+					session.record = record
+				} else {
+					expired = true
+				}
+			}
+			if value == "" || expired {
+				session.isNew = true
+				session.record = SessionRecord{Created: now, LastSeen: now}
+			}
+
+			commit := func() {
+				session.mu.Lock()
+				defer session.mu.Unlock()
+
+				cookie := &http.Cookie{
+					Name:     opts.Name,
+					Path:     opts.Path,
+					Domain:   opts.Domain,
+					Secure:   RequestScheme(r) == "https",
+					HttpOnly: true,
+					SameSite: opts.SameSite,
+				}
+
+				if session.destroyed || (expired && !session.modified) {
+					if value != "" {
+						opts.Store.Delete(value)
+						cookie.MaxAge = -1
+						http.SetCookie(w, cookie)
+					}
+					return
+				}
+
+				// new sessions aren't stored until something is put in them
+				touched := !session.isNew && now.Sub(session.record.LastSeen) >= opts.IdleTimeout/10
+				if !session.modified && !touched {
+					return
+				}
+
+				saveAs := value
+				if session.renewed || expired {
+					opts.Store.Delete(value)
+					saveAs = ""
+				}
+				session.record.LastSeen = now
+				remaining := opts.AbsoluteTimeout - now.Sub(session.record.Created)
+				saved, err := opts.Store.Save(saveAs, session.record, min(opts.IdleTimeout, remaining))
+				if err != nil {
+					return
+				}
+				cookie.Value = saved
+				cookie.MaxAge = int(remaining / time.Second)
+				http.SetCookie(w, cookie)
+			}
+
+			sw := &sessionWriter{ResponseWriter: WrapResponseWriter(w), commit: commit}
+			hijacker, _ := sw.ResponseWriter.(http.Hijacker)
+			pusher, _ := sw.ResponseWriter.(http.Pusher)
+			ww := exposeInterfaces(sw, w, writerInterfaces{
+				flusher:    (*sessionFlusher)(sw),
+				hijacker:   hijacker,
+				readerFrom: (*sessionReaderFrom)(sw),
+				pusher:     pusher,
+			})
+			next.ServeHTTP(ww, r.WithContext(context.WithValue(r.Context(), sessionKey, session)))
+			sw.before()
+		})
+	}
+}
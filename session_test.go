@@ -0,0 +1,224 @@
+package httprouter
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func createSessionTestRouter(opts SessionOptions) *ServerRouter {
+	r := NewRouter()
+	r.Use(SessionMiddleware(opts))
+	r.Post("/login", func(w http.ResponseWriter, r *http.Request) {
+		session := Session(r)
+		session.Renew()
+		session.Set("user", r.URL.Query().Get("user"))
+		session.AddFlash("welcome")
+		w.Write([]byte("logged in"))
+	})
+	r.Get("/whoami", func(w http.ResponseWriter, r *http.Request) {
+		session := Session(r)
+		w.Write([]byte(session.Get("user") + " " + strings.Join(session.Flashes(), ",")))
+	})
+	r.Post("/logout", func(w http.ResponseWriter, r *http.Request) {
+		Session(r).Destroy()
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return r
+}
+
+// sends a request with the session cookie and returns the body and the new cookie, if one was set
+func sessionRequest(r http.Handler, method string, url string, value string) (string, *http.Cookie) {
+	req := httptest.NewRequest(method, url, nil)
+	if value != "" {
+		req.AddCookie(&http.Cookie{Name: "session", Value: value})
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var cookie *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == "session" {
+			cookie = c
+		}
+	}
+	return w.Body.String(), cookie
+}
+
+func TestCookieSessions(t *testing.T) {
+	oldSecret := bytes.Repeat([]byte("o"), 32)
+	newSecret := bytes.Repeat([]byte("n"), 32)
+
+	now := time.Unix(1700000000, 0)
+	opts := SessionOptions{
+		Store:           NewCookieStore(oldSecret),
+		IdleTimeout:     30 * time.Minute,
+		AbsoluteTimeout: time.Hour,
+		Now:             func() time.Time { return now },
+	}
+	r := createSessionTestRouter(opts)
+
+	body, cookie := sessionRequest(r, "GET", "/whoami", "")
+	if body != " " || cookie != nil {
+		t.Errorf("Expected an empty session that isn't sent but got %q with cookie %v", body, cookie)
+	}
+
+	_, cookie = sessionRequest(r, "POST", "/login?user=alice", "")
+	if cookie == nil || !cookie.HttpOnly || cookie.MaxAge != 3600 || strings.Contains(cookie.Value, "alice") {
+		t.Fatalf("Expected an encrypted session cookie but got %v", cookie)
+	}
+	value := cookie.Value
+
+	body, cookie = sessionRequest(r, "GET", "/whoami", value)
+	if body != "alice welcome" || cookie == nil {
+		t.Fatalf("Expected the user and flash with the flash removed from the cookie but got %q with cookie %v", body, cookie)
+	}
+	value = cookie.Value
+
+	body, cookie = sessionRequest(r, "GET", "/whoami", value)
+	if body != "alice " || cookie != nil {
+		t.Errorf("Expected the unmodified session not to be sent but got %q with cookie %v", body, cookie)
+	}
+
+	tampered := []byte(value)
+	tampered[10] ^= 1
+	body, _ = sessionRequest(r, "GET", "/whoami", string(tampered))
+	if body != " " {
+		t.Errorf("Expected a tampered cookie to be rejected but got %q", body)
+	}
+
+	// the idle timeout is refreshed once a tenth of it has passed
+	now = now.Add(time.Minute)
+	if _, cookie = sessionRequest(r, "GET", "/whoami", value); cookie != nil {
+		t.Errorf("Expected the session not to be refreshed after a minute but got %v", cookie)
+	}
+	now = now.Add(3 * time.Minute)
+	if _, cookie = sessionRequest(r, "GET", "/whoami", value); cookie == nil {
+		t.Fatalf("Expected the session to be refreshed after 4 minutes")
+	}
+	value = cookie.Value
+
+	// sessions sealed with an old secret are still read after rotating
+	rotated := opts
+	rotated.Store = NewCookieStore(newSecret, oldSecret)
+	if body, _ = sessionRequest(createSessionTestRouter(rotated), "GET", "/whoami", value); body != "alice " {
+		t.Errorf("Expected the rotated store to read the session but got %q", body)
+	}
+	retired := opts
+	retired.Store = NewCookieStore(newSecret)
+	if body, _ = sessionRequest(createSessionTestRouter(retired), "GET", "/whoami", value); body != " " {
+		t.Errorf("Expected a retired secret to be rejected but got %q", body)
+	}
+
+	now = now.Add(31 * time.Minute)
+	body, cookie = sessionRequest(r, "GET", "/whoami", value)
+	if body != " " || cookie == nil || cookie.MaxAge != -1 {
+		t.Errorf("Expected the idle session to expire and be removed but got %q with cookie %v", body, cookie)
+	}
+}
+
+func TestSessionAbsoluteTimeout(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	r := createSessionTestRouter(SessionOptions{
+		Store:           NewCookieStore(bytes.Repeat([]byte("s"), 32)),
+		IdleTimeout:     time.Hour,
+		AbsoluteTimeout: 2 * time.Hour,
+		Now:             func() time.Time { return now },
+	})
+
+	_, cookie := sessionRequest(r, "POST", "/login?user=alice", "")
+	for i := 0; i < 3; i++ {
+		now = now.Add(50 * time.Minute)
+		body, next := sessionRequest(r, "GET", "/whoami", cookie.Value)
+		if i < 2 && (next == nil || !strings.HasPrefix(body, "alice")) {
+			t.Fatalf("Expected the session to be refreshed after %d minutes but got %q", (i+1)*50, body)
+		}
+		if i == 2 && (body != " " || next == nil || next.MaxAge != -1) {
+			t.Errorf("Expected the session to expire after the absolute timeout but got %q with cookie %v", body, next)
+		}
+		if next != nil {
+			cookie = next
+		}
+	}
+}
+
+func TestMemorySessions(t *testing.T) {
+	store := NewMemorySessionStore()
+	r := createSessionTestRouter(SessionOptions{Store: store})
+
+	_, cookie := sessionRequest(r, "POST", "/login?user=alice", "")
+	first := cookie.Value
+	if store.Len() != 1 || strings.Contains(first, ".") {
+		t.Fatalf("Expected the session to be stored on the server with an id cookie but got %q", first)
+	}
+
+	_, cookie = sessionRequest(r, "POST", "/login?user=bob", first)
+	second := cookie.Value
+	if second == first || store.Len() != 1 {
+		t.Errorf("Expected logging in to renew the session id but got %q from %q with %d sessions", second, first, store.Len())
+	}
+	if body, _ := sessionRequest(r, "GET", "/whoami", first); body != " " {
+		t.Errorf("Expected the old session id to be invalid but got %q", body)
+	}
+	if body, _ := sessionRequest(r, "GET", "/whoami", second); body != "bob welcome,welcome" {
+		t.Errorf("Expected the renewed session but got %q", body)
+	}
+
+	_, cookie = sessionRequest(r, "POST", "/logout", second)
+	if cookie == nil || cookie.MaxAge != -1 || store.Len() != 0 {
+		t.Errorf("Expected logging out to remove the session but got cookie %v with %d sessions", cookie, store.Len())
+	}
+
+	if Session(httptest.NewRequest("GET", "/", nil)) != nil {
+		t.Errorf("Expected no session without the middleware")
+	}
+}
+
+func TestSessionWriterInterfaces(t *testing.T) {
+	type Test struct {
+		w          http.ResponseWriter
+		flusher    bool
+		hijacker   bool
+		readerFrom bool
+		pusher     bool
+	}
+
+	testTable := []Test{
+		{w: plainWriter{httptest.NewRecorder()}},
+		{w: httptest.NewRecorder(), flusher: true},
+		{w: hijackWriter{httptest.NewRecorder()}, flusher: true, hijacker: true},
+		{w: fullWriter{httptest.NewRecorder()}, flusher: true, hijacker: true, readerFrom: true, pusher: true},
+	}
+
+	middleware := SessionMiddleware(SessionOptions{Store: NewMemorySessionStore()})
+	for i, test := range testTable {
+		var flusher, hijacker, readerFrom, pusher bool
+		handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, flusher = w.(http.Flusher)
+			_, hijacker = w.(http.Hijacker)
+			_, readerFrom = w.(io.ReaderFrom)
+			_, pusher = w.(http.Pusher)
+		}))
+		handler.ServeHTTP(test.w, httptest.NewRequest("GET", "/", nil))
+
+		if flusher != test.flusher || hijacker != test.hijacker || readerFrom != test.readerFrom || pusher != test.pusher {
+			t.Errorf("Failed test %d, expected interfaces %v %v %v %v, got %v %v %v %v", i,
+				test.flusher, test.hijacker, test.readerFrom, test.pusher, flusher, hijacker, readerFrom, pusher)
+		}
+	}
+
+	// the session is committed before a body copied with ReadFrom sends the header
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Session(r).Set("user", "alice")
+		w.(io.ReaderFrom).ReadFrom(strings.NewReader("hello"))
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(fullWriter{rec}, httptest.NewRequest("GET", "/", nil))
+	if len(rec.Result().Cookies()) != 1 || rec.Body.String() != "hello" {
+		t.Errorf("Expected the session cookie to be sent with the body but got %v %q", rec.Result().Cookies(), rec.Body.String())
+	}
+}
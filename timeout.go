@@ -0,0 +1,114 @@
+package httprouter
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type timeoutWriter struct {
+	w    http.ResponseWriter
+	h    http.Header
+	wbuf bytes.Buffer
+
+	mu          sync.Mutex
+	timedOut    bool
+	wroteHeader bool
+	code        int
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.h
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if !tw.wroteHeader {
+		tw.writeHeaderLocked(http.StatusOK)
+	}
+	return tw.wbuf.Write(p)
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+	tw.writeHeaderLocked(code)
+}
+
+func (tw *timeoutWriter) writeHeaderLocked(code int) {
+	tw.wroteHeader = true
+	tw.code = code
+}
+
+const timeoutMarker = "timeout"
+
+// TimeoutMiddleware bounds the time a handler can take. The response is buffered, and if the handler hasn't
+// finished by the deadline ErrTimeout is passed to the router's error handling instead. When a route has several
+// timeouts added with Use or With only the innermost one applies, so With can give a route a longer limit than its router.
+// Timeouts wrapped around a handler by hand always apply
+func TimeoutMiddleware(timeout time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		// identifies this timeout among the route's markers
+		layer := &struct{ timeout time.Duration }{timeout}
+
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if rt := matchedRoute(r); rt != nil && rt.meta.overridden(timeoutMarker, layer) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+
+			// the handler may outlive this function so it gets its own request rather than updating r
+			tr := r.WithContext(ctx)
+			tw := &timeoutWriter{w: w, h: make(http.Header)}
+
+			done := make(chan struct{})
+			panicChan := make(chan any, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicChan <- p
+					}
+				}()
+				next.ServeHTTP(tw, tr)
+				close(done)
+			}()
+
+			select {
+			case p := <-panicChan:
+				panic(p)
+			case <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				dst := w.Header()
+				for key, values := range tw.h {
+					dst[key] = values
+				}
+				if !tw.wroteHeader {
+					tw.code = http.StatusOK
+				}
+				w.WriteHeader(tw.code)
+				w.Write(tw.wbuf.Bytes())
+			case <-ctx.Done():
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				tw.timedOut = true
+				if ctx.Err() == context.DeadlineExceeded {
+					HandleError(w, r, ErrTimeout)
+				}
+			}
+		})
+		return &routeMarker{Handler: handler, key: timeoutMarker, value: layer}
+	}
+}
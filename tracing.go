@@ -0,0 +1,272 @@
+package httprouter
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SpanContext identifies a span as propagated by the W3C traceparent and tracestate headers
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Flags   byte
+	State   string
+}
+
+const sampledFlag = 0x01
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+func (sc SpanContext) Sampled() bool {
+	return sc.Flags&sampledFlag != 0
+}
+
+// TraceParent formats the span context as a version 00 traceparent header
+func (sc SpanContext) TraceParent() string {
+	return fmt.Sprintf("00-%x-%x-%02x", sc.TraceID, sc.SpanID, sc.Flags)
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !(s[i] >= '0' && s[i] <= '9' || s[i] >= 'a' && s[i] <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseTraceParent parses a traceparent header, later versions are parsed as version 00 as the spec requires
+func ParseTraceParent(value string) (SpanContext, bool) {
+	var sc SpanContext
+	value = strings.TrimSpace(value)
+	if len(value) < 55 || !isLowerHex(value[:2]) || value[:2] == "ff" || (value[:2] == "00" && len(value) != 55) {
+		return sc, false
+	}
+	if len(value) > 55 && value[55] != '-' {
+		return sc, false
+	}
+	if value[2] != '-' || value[35] != '-' || value[52] != '-' {
+		return sc, false
+	}
+	traceID, spanID, flags := value[3:35], value[36:52], value[53:55]
+	if !isLowerHex(traceID) || !isLowerHex(spanID) || !isLowerHex(flags) {
+		return sc, false
+	}
+	hex.Decode(sc.TraceID[:], []byte(traceID))
+	hex.Decode(sc.SpanID[:], []byte(spanID))
+	var f [1]byte
+	hex.Decode(f[:], []byte(flags))
+	sc.Flags = f[0]
+	return sc, sc.IsValid()
+}
+
+// the spec allows vendors to drop tracestate that is too long rather than truncating it
+func parseTraceState(values []string) string {
+	state := strings.Join(values, ",")
+	if len(state) > 512 || strings.Count(state, ",") >= 32 {
+		return ""
+	}
+	return state
+}
+
+// NewSpanContext creates the context of a child of parent, or of a new sampled trace if parent isn't valid
+func NewSpanContext(parent SpanContext) SpanContext {
+	sc := SpanContext{Flags: sampledFlag}
+	if parent.IsValid() {
+		sc.TraceID = parent.TraceID
+		sc.Flags = parent.Flags
+		sc.State = parent.State
+	} else {
+		rand.Read(sc.TraceID[:])
+	}
+	rand.Read(sc.SpanID[:])
+	return sc
+}
+
+// Span records the work done for a request
+type Span interface {
+	SpanContext() SpanContext
+	SetAttribute(key string, value any)
+	SetStatus(status int)
+	RecordError(err error)
+	End()
+}
+
+// Tracer adapts a tracing library to the router, parent is invalid for requests that don't continue a trace.
+// The returned context is passed to the handler so the library can find its span
+type Tracer interface {
+	Start(ctx context.Context, name string, parent SpanContext) (context.Context, Span)
+}
+
+// TraceContext is the span context of the request's span, or an invalid span context if it isn't traced
+func TraceContext(ctx context.Context) SpanContext {
+	sc, _ := ctx.Value(traceKey).(SpanContext)
+	return sc
+}
+
+// InjectTraceContext sets the traceparent and tracestate headers of an outgoing request to continue the trace in ctx
+func InjectTraceContext(ctx context.Context, header http.Header) {
+	sc := TraceContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+	header.Set("traceparent", sc.TraceParent())
+	if sc.State != "" {
+		header.Set("tracestate", sc.State)
+	} else {
+		header.Del("tracestate")
+	}
+}
+
+// TracingMiddleware starts a span for each request named after the method and matched route pattern,
+// continuing the trace from the traceparent and tracestate headers. Errors passed to HandleError are recorded on the span
+func TracingMiddleware(tracer Tracer) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			parent, ok := ParseTraceParent(r.Header.Get("traceparent"))
+			if ok {
+				parent.State = parseTraceState(r.Header.Values("tracestate"))
+			}
+
+			name := r.Method
+			route := routePattern(r)
+			if route != "" {
+				name += " " + route
+			}
+
+			ctx, span := tracer.Start(r.Context(), name, parent)
+			span.SetAttribute("http.request.method", r.Method)
+			span.SetAttribute("url.path", r.URL.Path)
+			if route != "" {
+				span.SetAttribute("http.route", route)
+			}
+
+			errorHandler, ok := r.Context().Value(errorHandlerKey).(ErrorHandlerFunc)
+			if !ok {
+				errorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+					TextErrorRenderer(w, r, toHTTPError(err))
+				}
+			}
+			ctx = context.WithValue(ctx, errorHandlerKey, ErrorHandlerFunc(func(w http.ResponseWriter, r *http.Request, err error) {
+				span.RecordError(err)
+				errorHandler(w, r, err)
+			}))
+			ctx = context.WithValue(ctx, traceKey, span.SpanContext())
+
+			ww := WrapResponseWriter(w)
+			defer func() {
+				if p := recover(); p != nil {
+					span.RecordError(fmt.Errorf("panic: %v", p))
+					span.SetStatus(http.StatusInternalServerError)
+					span.End()
+					panic(p)
+				}
+				span.SetAttribute("http.response.status_code", ww.Status())
+				span.SetStatus(ww.Status())
+				span.End()
+			}()
+			next.ServeHTTP(ww, r.WithContext(ctx))
+		})
+	}
+}
+
+// RecordedSpan is a span recorded by a RecordingTracer
+type RecordedSpan struct {
+	Name        string
+	SpanContext SpanContext
+	Parent      SpanContext
+	Attributes  map[string]any
+	Status      int
+	Errors      []error
+	Start       time.Time
+	End         time.Time
+}
+
+// RecordingTracer keeps ended spans in memory for tests
+type RecordingTracer struct {
+	mu    sync.Mutex
+	spans []RecordedSpan
+}
+
+type recordingSpan struct {
+	tracer *RecordingTracer
+	mu     sync.Mutex
+	span   RecordedSpan
+	ended  bool
+}
+
+func NewRecordingTracer() *RecordingTracer {
+	return &RecordingTracer{}
+}
+
+func (tracer *RecordingTracer) Start(ctx context.Context, name string, parent SpanContext) (context.Context, Span) {
+	return ctx, &recordingSpan{
+		tracer: tracer,
+		span: RecordedSpan{
+			Name:        name,
+			SpanContext: NewSpanContext(parent),
+			Parent:      parent,
+			Attributes:  make(map[string]any),
+			Start:       time.Now(),
+		},
+	}
+}
+
+// Spans returns the ended spans in the order they ended
+func (tracer *RecordingTracer) Spans() []RecordedSpan {
+	tracer.mu.Lock()
+	defer tracer.mu.Unlock()
+	return append([]RecordedSpan{}, tracer.spans...)
+}
+
+func (tracer *RecordingTracer) Reset() {
+	tracer.mu.Lock()
+	defer tracer.mu.Unlock()
+	tracer.spans = nil
+}
+
+func (span *recordingSpan) SpanContext() SpanContext {
+	return span.span.SpanContext
+}
+
+func (span *recordingSpan) SetAttribute(key string, value any) {
+	span.mu.Lock()
+	defer span.mu.Unlock()
+	span.span.Attributes[key] = value
+}
+
+func (span *recordingSpan) SetStatus(status int) {
+	span.mu.Lock()
+	defer span.mu.Unlock()
+	span.span.Status = status
+}
+
+func (span *recordingSpan) RecordError(err error) {
+	span.mu.Lock()
+	defer span.mu.Unlock()
+	span.span.Errors = append(span.span.Errors, err)
+}
+
+func (span *recordingSpan) End() {
+	span.mu.Lock()
+	if span.ended {
+		span.mu.Unlock()
+		return
+	}
+	span.ended = true
+	span.span.End = time.Now()
+	recorded := span.span
+	span.mu.Unlock()
+
+	span.tracer.mu.Lock()
+	defer span.tracer.mu.Unlock()
+	span.tracer.spans = append(span.tracer.spans, recorded)
+}
@@ -0,0 +1,104 @@
+package httprouter
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	type Test struct {
+		in    string
+		valid bool
+	}
+
+	testTable := []Test{
+		{in: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", valid: true},
+		{in: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", valid: true},
+		{in: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future", valid: true},
+		{in: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", valid: false},
+		{in: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01extra", valid: false},
+		{in: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", valid: false},
+		{in: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", valid: false},
+		{in: "00-00000000000000000000000000000000-00f067aa0ba902b7-01", valid: false},
+		{in: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", valid: false},
+		{in: "00-4bf92f3577b34da6a3ce929d0e0e4736_00f067aa0ba902b7-01", valid: false},
+		{in: "", valid: false},
+	}
+
+	for i, test := range testTable {
+		sc, ok := ParseTraceParent(test.in)
+		if ok != test.valid {
+			t.Errorf("Failed test %d, expected %q to be valid %t", i, test.in, test.valid)
+		}
+		if ok && test.in[:2] == "00" && sc.TraceParent() != test.in {
+			t.Errorf("Failed test %d, expected %q to round trip but got %q", i, test.in, sc.TraceParent())
+		}
+	}
+}
+
+func TestTracingMiddleware(t *testing.T) {
+	tracer := NewRecordingTracer()
+
+	r := NewRouter()
+	r.Use(RecoveryMiddleware(log.New(&bytes.Buffer{}, "", 0)))
+	r.Use(TracingMiddleware(tracer))
+	r.Get("/users", func(w http.ResponseWriter, r *http.Request) {
+		outgoing := http.Header{}
+		InjectTraceContext(r.Context(), outgoing)
+		w.Write([]byte(outgoing.Get("traceparent") + " " + outgoing.Get("tracestate")))
+	})
+	r.RouteE("GET", "/fail", func(w http.ResponseWriter, r *http.Request) error {
+		return ErrForbidden
+	})
+	r.Get("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("something went wrong")
+	})
+
+	req := httptest.NewRequest("GET", "/users", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Add("tracestate", "vendor=a")
+	req.Header.Add("tracestate", "other=b")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	spans := tracer.Spans()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span but got %d", len(spans))
+	}
+	span := spans[0]
+	parent, _ := ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if span.Name != "GET /users" || span.Status != http.StatusOK || span.Attributes["http.route"] != "/users" {
+		t.Errorf("Expected a span for GET /users with status 200 but got %+v", span)
+	}
+	if span.Parent.SpanID != parent.SpanID || span.SpanContext.TraceID != parent.TraceID || span.SpanContext.SpanID == parent.SpanID {
+		t.Errorf("Expected a child span of the traceparent but got %+v", span)
+	}
+	if expected := span.SpanContext.TraceParent() + " vendor=a,other=b"; w.Body.String() != expected {
+		t.Errorf("Expected the outgoing trace context %q but got %q", expected, w.Body.String())
+	}
+
+	tracer.Reset()
+	for _, url := range []string{"/fail", "/panic", "/users"} {
+		req = httptest.NewRequest("GET", url, nil)
+		req.Header.Set("traceparent", "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	spans = tracer.Spans()
+	if len(spans) != 3 {
+		t.Fatalf("Expected 3 spans but got %d", len(spans))
+	}
+	if spans[0].Status != http.StatusForbidden || len(spans[0].Errors) != 1 || !errors.Is(spans[0].Errors[0], ErrForbidden) {
+		t.Errorf("Expected the handler error to be recorded but got %+v", spans[0])
+	}
+	if spans[1].Status != http.StatusInternalServerError || len(spans[1].Errors) != 1 || spans[1].Errors[0].Error() != "panic: something went wrong" {
+		t.Errorf("Expected the panic to be recorded but got %+v", spans[1])
+	}
+	if spans[2].Parent.IsValid() || !spans[2].SpanContext.IsValid() || !spans[2].SpanContext.Sampled() {
+		t.Errorf("Expected an invalid traceparent to start a new sampled trace but got %+v", spans[2])
+	}
+}
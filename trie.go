@@ -1,7 +1,6 @@
 package httprouter
 
 import (
-	"errors"
 	"fmt"
 	"regexp"
 	"strings"
@@ -9,6 +8,7 @@ import (
 
 type Trie[v any] struct {
 	roots      map[string]*[]Node[v]
+	methods    []string
 	regexCache map[string]*regexp.Regexp
 }
 
@@ -38,6 +38,7 @@ func (trie *Trie[v]) findRoot(method string) *[]Node[v] {
 	if !ok {
 		nodes := make([]Node[v], 0)
 		trie.roots[method] = &nodes
+		trie.methods = append(trie.methods, method)
 		return &nodes
 	}
 	return nodes
@@ -140,7 +141,7 @@ func (trie *Trie[v]) getRegex(regexStr string) (*regexp.Regexp, error) {
 		if !ok {
 			rexp, err := regexp.Compile(regexStr)
 			if err != nil {
-				return nil, fmt.Errorf("failed to compile regex %s", regexStr)
+				return nil, &PatternError{Pattern: regexStr, Err: err}
 			}
 			trie.regexCache[regexStr] = rexp
 			re = rexp
@@ -199,7 +200,10 @@ func extractValue(relIdx int, path string) (int, string) {
 }
 
 func (trie *Trie[v]) find(method string, path string) (*v, error) {
-	nodes := trie.findRoot(method)
+	nodes, ok := trie.roots[method]
+	if !ok {
+		return nil, nil
+	}
 
 	keepSearching := true
 	pathIndex := 0
@@ -234,7 +238,7 @@ func (trie *Trie[v]) find(method string, path string) (*v, error) {
 					return nil, nil
 				} else {
 					// unkown case
-					return nil, errors.New("unknown case for finding node in radix trie: this is a bug")
+					return nil, fmt.Errorf("%w: unknown case for finding node in radix trie: this is a bug", ErrInternal)
 				}
 			}
 		}
@@ -243,16 +247,105 @@ func (trie *Trie[v]) find(method string, path string) (*v, error) {
 	return nil, nil
 }
 
+// finds the value of the deepest node whose path is a prefix of the path ending on a segment boundary,
+// along with the remainder of the path that was not matched
+func (trie *Trie[v]) findPrefix(method string, path string) (*v, string, error) {
+	nodes, ok := trie.roots[method]
+	if !ok {
+		return nil, path, nil
+	}
+
+	var value *v
+	rest := path
+
+	keepSearching := true
+	pathIndex := 0
+	for keepSearching {
+		keepSearching = false
+		for i := range *nodes {
+			curr := &(*nodes)[i]
+
+			p := 0
+			for (pathIndex+p) < len(path) && p < len(curr.path) {
+				if path[pathIndex+p] != curr.path[p] {
+					break
+				}
+				p += 1
+			}
+
+			if p != 0 {
+				if pathIndex+p == len(path) && p == len(curr.path) {
+					// case 1: ins path is the same as the curr path - this is the deepest possible match
+					if curr.value != nil {
+						return curr.value, "", nil
+					}
+					return value, rest, nil
+				} else if pathIndex+p == len(path) && p < len(curr.path) {
+					// case 2: ins path fits inside the curr path - the last match is the deepest
+					return value, rest, nil
+				} else if pathIndex+p < len(path) && p == len(curr.path) {
+					// case 3: curr path fits inside the ins path - remember the match and traverse curr node's children
+					pathIndex += p
+					if curr.value != nil && isSegmentBoundary(path, pathIndex) {
+						value = curr.value
+						rest = path[pathIndex:]
+					}
+					nodes = &curr.children
+					keepSearching = true
+					break
+				} else if pathIndex+p < len(path) && p < len(curr.path) {
+					// case 4: neither path reaches the end - the last match is the deepest
+					return value, rest, nil
+				} else {
+					// unkown case
+					return nil, path, fmt.Errorf("%w: unknown case for finding prefix in radix trie: this is a bug", ErrInternal)
+				}
+			}
+		}
+	}
+
+	return value, rest, nil
+}
+
+func isSegmentBoundary(path string, index int) bool {
+	return index == len(path) || path[index] == '/' || path[index-1] == '/'
+}
+
 func (trie *Trie[v]) routes() []string {
 	routes := make([]string, 0)
-	for key, root := range trie.roots {
-		for _, child := range *root {
+	for _, key := range trie.methods {
+		for _, child := range *trie.roots[key] {
 			child.routes(key+" "+child.path, &routes)
 		}
 	}
 	return routes
 }
 
+func (trie *Trie[v]) walk(fn func(value *v) error) error {
+	for _, key := range trie.methods {
+		for i := range *trie.roots[key] {
+			if err := (*trie.roots[key])[i].walk(fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (node *Node[v]) walk(fn func(value *v) error) error {
+	if node.value != nil {
+		if err := fn(node.value); err != nil {
+			return err
+		}
+	}
+	for i := range node.children {
+		if err := node.children[i].walk(fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (node *Node[v]) routes(path string, routes *[]string) {
 	if node.value != nil {
 		*routes = append(*routes, path)
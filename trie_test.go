@@ -163,6 +163,60 @@ func TestTrieFind(t *testing.T) {
 	}
 }
 
+func TestTrieFindPrefix(t *testing.T) {
+	trie := newTrie[int]()
+
+	trie.insert("GET", "/api", 0)
+	trie.insert("GET", "/api/users", 1)
+	trie.insert("GET", "/api/users/admin", 2)
+	trie.insert("GET", "/static/", 3)
+	trie.insert("GET", "/apiary", 4)
+
+	type Test struct {
+		in   string
+		out  *int
+		rest string
+	}
+
+	testTable := []Test{
+		{in: "/", out: nil, rest: "/"},
+		{in: "/api", out: intPtr(0), rest: ""},
+		{in: "/api/", out: intPtr(0), rest: "/"},
+		{in: "/api/orders/1", out: intPtr(0), rest: "/orders/1"},
+		{in: "/api/users/1", out: intPtr(1), rest: "/1"},
+		{in: "/api/usersx", out: intPtr(0), rest: "/usersx"},
+		{in: "/api/users/admin/settings", out: intPtr(2), rest: "/settings"},
+		{in: "/apiar", out: nil, rest: "/apiar"},
+		{in: "/apiary/hive", out: intPtr(4), rest: "/hive"},
+		{in: "/static/css/main.css", out: intPtr(3), rest: "css/main.css"},
+		{in: "/other", out: nil, rest: "/other"},
+	}
+
+	for _, test := range testTable {
+		value, rest, err := trie.findPrefix("GET", test.in)
+		if err != nil {
+			t.Errorf("Error for path %s, got %v", test.in, err)
+		}
+
+		if value != nil || test.out != nil {
+			if value == nil {
+				t.Errorf("Expected to find %v in the Trie structure for path %s but got nil", *test.out, test.in)
+			} else if test.out == nil {
+				t.Errorf("Expected to find nil in the Trie structure for path %s but got %v", test.in, *value)
+			} else if *value != *test.out {
+				t.Errorf("Expected to find %v in the Trie structure for path %s but got %v", *test.out, test.in, *value)
+			}
+		}
+		if rest != test.rest {
+			t.Errorf("Expected remainder %q for path %s but got %q", test.rest, test.in, rest)
+		}
+	}
+
+	if value, rest, _ := trie.findPrefix("POST", "/api"); value != nil || rest != "/api" {
+		t.Errorf("Expected no match for an unregistered method")
+	}
+}
+
 func TestTrieRoutes(t *testing.T) {
 	trie := newTrie[int]()
 
@@ -0,0 +1,264 @@
+package httprouter
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ResponseWriter records the status, size and time to first byte of a response
+type ResponseWriter interface {
+	http.ResponseWriter
+
+	// Status is the status code sent to the client, or 200 if nothing has been written yet
+	Status() int
+
+	BytesWritten() int64
+
+	// Written reports whether the status line and headers have been sent
+	Written() bool
+
+	TimeToFirstByte() time.Duration
+
+	Unwrap() http.ResponseWriter
+}
+
+type responseWriter struct {
+	w         http.ResponseWriter
+	status    int
+	bytes     int64
+	start     time.Time
+	firstByte time.Time
+}
+
+const (
+	flusherFlag = 1 << iota
+	hijackerFlag
+	readerFromFlag
+	pusherFlag
+)
+
+// WrapResponseWriter wraps the writer so the returned writer implements exactly the same optional interfaces
+// (http.Flusher, http.Hijacker, io.ReaderFrom and http.Pusher) as the original
+func WrapResponseWriter(w http.ResponseWriter) ResponseWriter {
+	rw := &responseWriter{w: w, start: time.Now()}
+	return exposeInterfaces(rw, w, writerInterfaces{
+		flusher:    (*flusher)(rw),
+		hijacker:   (*hijacker)(rw),
+		readerFrom: (*readerFrom)(rw),
+		pusher:     (*pusher)(rw),
+	})
+}
+
+// the implementations of the optional interfaces a wrapping writer provides, each is only exposed when the wrapped
+// writer has the interface too
+type writerInterfaces struct {
+	flusher    http.Flusher
+	hijacker   http.Hijacker
+	readerFrom io.ReaderFrom
+	pusher     http.Pusher
+}
+
+// exposes on rw the optional interfaces that w implements, so middlewares that replace the response writer don't hide
+// them from handlers or claim ones the connection doesn't have
+func exposeInterfaces(rw ResponseWriter, w http.ResponseWriter, ext writerInterfaces) ResponseWriter {
+	flags := 0
+	if _, ok := w.(http.Flusher); ok {
+		flags |= flusherFlag
+	}
+	if _, ok := w.(http.Hijacker); ok {
+		flags |= hijackerFlag
+	}
+	if _, ok := w.(io.ReaderFrom); ok {
+		flags |= readerFromFlag
+	}
+	if _, ok := w.(http.Pusher); ok {
+		flags |= pusherFlag
+	}
+
+	switch flags {
+	case flusherFlag:
+		return struct {
+			ResponseWriter
+			http.Flusher
+		}{rw, ext.flusher}
+	case hijackerFlag:
+		return struct {
+			ResponseWriter
+			http.Hijacker
+		}{rw, ext.hijacker}
+	case flusherFlag | hijackerFlag:
+		return struct {
+			ResponseWriter
+			http.Flusher
+			http.Hijacker
+		}{rw, ext.flusher, ext.hijacker}
+	case readerFromFlag:
+		return struct {
+			ResponseWriter
+			io.ReaderFrom
+		}{rw, ext.readerFrom}
+	case flusherFlag | readerFromFlag:
+		return struct {
+			ResponseWriter
+			http.Flusher
+			io.ReaderFrom
+		}{rw, ext.flusher, ext.readerFrom}
+	case hijackerFlag | readerFromFlag:
+		return struct {
+			ResponseWriter
+			http.Hijacker
+			io.ReaderFrom
+		}{rw, ext.hijacker, ext.readerFrom}
+	case flusherFlag | hijackerFlag | readerFromFlag:
+		return struct {
+			ResponseWriter
+			http.Flusher
+			http.Hijacker
+			io.ReaderFrom
+		}{rw, ext.flusher, ext.hijacker, ext.readerFrom}
+	case pusherFlag:
+		return struct {
+			ResponseWriter
+			http.Pusher
+		}{rw, ext.pusher}
+	case flusherFlag | pusherFlag:
+		return struct {
+			ResponseWriter
+			http.Flusher
+			http.Pusher
+		}{rw, ext.flusher, ext.pusher}
+	case hijackerFlag | pusherFlag:
+		return struct {
+			ResponseWriter
+			http.Hijacker
+			http.Pusher
+		}{rw, ext.hijacker, ext.pusher}
+	case flusherFlag | hijackerFlag | pusherFlag:
+		return struct {
+			ResponseWriter
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+		}{rw, ext.flusher, ext.hijacker, ext.pusher}
+	case readerFromFlag | pusherFlag:
+		return struct {
+			ResponseWriter
+			io.ReaderFrom
+			http.Pusher
+		}{rw, ext.readerFrom, ext.pusher}
+	case flusherFlag | readerFromFlag | pusherFlag:
+		return struct {
+			ResponseWriter
+			http.Flusher
+			io.ReaderFrom
+			http.Pusher
+		}{rw, ext.flusher, ext.readerFrom, ext.pusher}
+	case hijackerFlag | readerFromFlag | pusherFlag:
+		return struct {
+			ResponseWriter
+			http.Hijacker
+			io.ReaderFrom
+			http.Pusher
+		}{rw, ext.hijacker, ext.readerFrom, ext.pusher}
+	case flusherFlag | hijackerFlag | readerFromFlag | pusherFlag:
+		return struct {
+			ResponseWriter
+			http.Flusher
+			http.Hijacker
+			io.ReaderFrom
+			http.Pusher
+		}{rw, ext.flusher, ext.hijacker, ext.readerFrom, ext.pusher}
+	default:
+		return rw
+	}
+}
+
+func (rw *responseWriter) Header() http.Header {
+	return rw.w.Header()
+}
+
+func (rw *responseWriter) WriteHeader(status int) {
+	// informational responses can be followed by the final response so they aren't recorded
+	if !rw.Written() && (status >= 200 || status == http.StatusSwitchingProtocols) {
+		rw.status = status
+		rw.firstByte = time.Now()
+	}
+	rw.w.WriteHeader(status)
+}
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	rw.writeImplicitHeader()
+	n, err := rw.w.Write(b)
+	rw.bytes += int64(n)
+	return n, err
+}
+
+func (rw *responseWriter) writeImplicitHeader() {
+	if !rw.Written() {
+		rw.status = http.StatusOK
+		rw.firstByte = time.Now()
+	}
+}
+
+func (rw *responseWriter) Status() int {
+	if rw.status == 0 {
+		return http.StatusOK
+	}
+	return rw.status
+}
+
+func (rw *responseWriter) BytesWritten() int64 {
+	return rw.bytes
+}
+
+func (rw *responseWriter) Written() bool {
+	return rw.status != 0
+}
+
+func (rw *responseWriter) TimeToFirstByte() time.Duration {
+	if !rw.Written() {
+		return 0
+	}
+	return rw.firstByte.Sub(rw.start)
+}
+
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.w
+}
+
+// the optional interfaces are implemented on distinct types so they are only exposed when the original writer has them
+
+type flusher responseWriter
+
+func (f *flusher) Flush() {
+	rw := (*responseWriter)(f)
+	rw.writeImplicitHeader()
+	rw.w.(http.Flusher).Flush()
+}
+
+type hijacker responseWriter
+
+func (h *hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	rw := (*responseWriter)(h)
+	return rw.w.(http.Hijacker).Hijack()
+}
+
+type readerFrom responseWriter
+
+func (rf *readerFrom) ReadFrom(src io.Reader) (int64, error) {
+	rw := (*responseWriter)(rf)
+	rw.writeImplicitHeader()
+	n, err := rw.w.(io.ReaderFrom).ReadFrom(src)
+	rw.bytes += n
+	return n, err
+}
+
+type pusher responseWriter
+
+func (p *pusher) Push(target string, opts *http.PushOptions) error {
+	rw := (*responseWriter)(p)
+	return rw.w.(http.Pusher).Push(target, opts)
+}
@@ -0,0 +1,151 @@
+package httprouter
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type plainWriter struct {
+	http.ResponseWriter
+}
+
+type hijackWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (w hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, nil
+}
+
+type fullWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (w fullWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, nil
+}
+
+func (w fullWriter) ReadFrom(src io.Reader) (int64, error) {
+	return io.Copy(w.ResponseRecorder, src)
+}
+
+func (w fullWriter) Push(target string, opts *http.PushOptions) error {
+	return nil
+}
+
+type deadlineWriter struct {
+	http.ResponseWriter
+	deadline time.Time
+}
+
+func (w *deadlineWriter) SetWriteDeadline(deadline time.Time) error {
+	w.deadline = deadline
+	return nil
+}
+
+func TestWrapResponseWriterInterfaces(t *testing.T) {
+	type Test struct {
+		w          http.ResponseWriter
+		flusher    bool
+		hijacker   bool
+		readerFrom bool
+		pusher     bool
+	}
+
+	testTable := []Test{
+		{w: plainWriter{httptest.NewRecorder()}},
+		{w: httptest.NewRecorder(), flusher: true},
+		{w: hijackWriter{httptest.NewRecorder()}, flusher: true, hijacker: true},
+		{w: fullWriter{httptest.NewRecorder()}, flusher: true, hijacker: true, readerFrom: true, pusher: true},
+	}
+
+	for i, test := range testTable {
+		rw := WrapResponseWriter(test.w)
+
+		_, flusher := rw.(http.Flusher)
+		_, hijacker := rw.(http.Hijacker)
+		_, readerFrom := rw.(io.ReaderFrom)
+		_, pusher := rw.(http.Pusher)
+
+		if flusher != test.flusher || hijacker != test.hijacker || readerFrom != test.readerFrom || pusher != test.pusher {
+			t.Errorf("Failed test %d, expected interfaces %v %v %v %v, got %v %v %v %v", i,
+				test.flusher, test.hijacker, test.readerFrom, test.pusher, flusher, hijacker, readerFrom, pusher)
+		}
+		if rw.Unwrap() != test.w {
+			t.Errorf("Failed test %d, expected Unwrap to return the original writer", i)
+		}
+	}
+}
+
+func TestWrapResponseWriterServer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := WrapResponseWriter(w)
+
+		_, flusher := rw.(http.Flusher)
+		_, hijacker := rw.(http.Hijacker)
+		_, readerFrom := rw.(io.ReaderFrom)
+		if !flusher || !hijacker || !readerFrom {
+			t.Errorf("Expected the wrapped server writer to keep its interfaces, got %v %v %v", flusher, hijacker, readerFrom)
+		}
+		rw.Write([]byte("Hello"))
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to send GET request: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestWrapResponseWriterRecords(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rw := WrapResponseWriter(fullWriter{rec})
+
+	if rw.Written() || rw.Status() != http.StatusOK || rw.TimeToFirstByte() != 0 {
+		t.Errorf("Expected nothing to be recorded before writing")
+	}
+
+	rw.WriteHeader(http.StatusCreated)
+	rw.Write([]byte("Hello "))
+	rw.(io.ReaderFrom).ReadFrom(strings.NewReader("World"))
+	rw.WriteHeader(http.StatusInternalServerError)
+
+	if !rw.Written() || rw.Status() != http.StatusCreated || rw.BytesWritten() != 11 {
+		t.Errorf("Expected status 201 and 11 bytes but got %d and %d", rw.Status(), rw.BytesWritten())
+	}
+	if rec.Body.String() != "Hello World" {
+		t.Errorf("Expected body Hello World but got %s", rec.Body.String())
+	}
+
+	rw = WrapResponseWriter(plainWriter{httptest.NewRecorder()})
+	rw.WriteHeader(http.StatusEarlyHints)
+	if rw.Written() {
+		t.Errorf("Expected informational responses to not be recorded")
+	}
+
+	rw = WrapResponseWriter(httptest.NewRecorder())
+	rw.(http.Flusher).Flush()
+	if !rw.Written() || rw.Status() != http.StatusOK {
+		t.Errorf("Expected a flush to send an implicit 200 but got %d", rw.Status())
+	}
+}
+
+func TestWrapResponseWriterController(t *testing.T) {
+	dw := &deadlineWriter{ResponseWriter: httptest.NewRecorder()}
+	rw := WrapResponseWriter(dw)
+
+	deadline := time.Now().Add(time.Minute)
+	if err := http.NewResponseController(rw).SetWriteDeadline(deadline); err != nil {
+		t.Fatalf("Expected the response controller to unwrap the writer but got %v", err)
+	}
+	if !dw.deadline.Equal(deadline) {
+		t.Errorf("Expected the deadline to be set on the original writer")
+	}
+}